@@ -0,0 +1,158 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import "sync"
+
+// Tracks the active source on the bus, i.e. the device the TV currently shows.
+type activeSourceState struct {
+	mtx      sync.Mutex
+	addr     PhysicalAddress
+	known    bool
+	watchers []chan struct{}
+	closed   bool
+}
+
+// Sets the active source and notifies all watchers if it changed.
+func (s *activeSourceState) set(addr PhysicalAddress, known bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.addr == addr && s.known == known {
+		return
+	}
+	s.addr, s.known = addr, known
+	for _, w := range s.watchers {
+		// A pending notification already covers this change.
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *activeSourceState) get() (PhysicalAddress, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.addr, s.known
+}
+
+func (s *activeSourceState) watch() <-chan struct{} {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	w := make(chan struct{}, 1)
+	if s.closed {
+		close(w)
+	} else {
+		s.watchers = append(s.watchers, w)
+	}
+	return w
+}
+
+func (s *activeSourceState) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for _, w := range s.watchers {
+		close(w)
+	}
+	s.watchers = nil
+}
+
+// Returns the physical address of the current active source. The second return value is false if
+// the active source is unknown, e.g. because no device announced itself yet or the active source
+// went inactive.
+//
+// The active source is tracked by following ActiveSource, InactiveSource, RoutingChange,
+// RoutingInformation, and SetStreamPath messages on the bus while Run is running.
+func (x *Cec) ActiveSource() (PhysicalAddress, bool) {
+	return x.active.get()
+}
+
+// Returns true if this device is the current active source.
+func (x *Cec) IsActiveSource() bool {
+	addr, ok := x.active.get()
+	return ok && addr == x.dev.GetPhysicalAddress()
+}
+
+// Returns a channel that receives a value whenever the active source changes. Changes that happen
+// while a notification is pending are coalesced, use ActiveSource to get the current state. The
+// channel is closed when the Cec is closed.
+func (x *Cec) WatchActiveSource() <-chan struct{} {
+	return x.active.watch()
+}
+
+// Updates the active source from an incoming message. Returns true if the message was handled.
+func (x *Cec) trackActiveSource(msg Message) bool {
+	switch cmd := msg.Cmd.(type) {
+	case ActiveSource:
+		x.active.set(cmd.Addr, true)
+
+	case InactiveSource:
+		if addr, ok := x.active.get(); ok && addr == cmd.Addr {
+			x.active.set(0, false)
+		}
+
+	case RoutingChange:
+		x.active.set(cmd.To, true)
+
+	case RoutingInformation:
+		x.active.set(cmd.Addr, true)
+
+	case SetStreamPath:
+		x.active.set(cmd.Addr, true)
+
+	case RequestActiveSource:
+		if x.IsActiveSource() {
+			x.Send(Broadcast, ActiveSource{Addr: x.dev.GetPhysicalAddress()})
+			return true
+		}
+	}
+	return false
+}
+
+// The ActiveSourceHandler answers SetStreamPath requests for this device by announcing it as the
+// active source. It's meant for sources like playback devices, switches use a SwitchHandler
+// instead. Handlers added before it can take SetStreamPath first, e.g. to wake up from standby.
+type ActiveSourceHandler struct{}
+
+// ActiveSourceHandler implements Handler.
+func (h ActiveSourceHandler) HandleMessage(x *Cec, msg Message) bool {
+	cmd, ok := msg.Cmd.(SetStreamPath)
+	if !ok || cmd.Addr != x.dev.GetPhysicalAddress() {
+		return false
+	}
+	// The TV asks us to become the active source.
+	x.Reply(Broadcast, ActiveSource{Addr: cmd.Addr})
+	return true
+}
+
+// Updates the active source from a message sent by this device.
+func (x *Cec) trackOutgoing(cmd Command) {
+	switch cmd := cmd.(type) {
+	case ActiveSource:
+		x.active.set(cmd.Addr, true)
+	case InactiveSource:
+		if addr, ok := x.active.get(); ok && addr == cmd.Addr {
+			x.active.set(0, false)
+		}
+	case RoutingChange:
+		x.active.set(cmd.To, true)
+	case RoutingInformation:
+		x.active.set(cmd.Addr, true)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestActiveSource(t *testing.T) {
+	us := fake.PhysicalAddress
+	tests := []struct {
+		name   string
+		in     []Packet
+		addr   PhysicalAddress
+		known  bool
+		active bool
+		out    []Packet
+	}{
+		{
+			name:  "unknown",
+			known: false,
+			out:   []Packet{},
+		}, {
+			name: "active_source",
+			in: []Packet{
+				{Playback2, Broadcast, OpActiveSource, []byte{0x20, 0x00}},
+			},
+			addr:  0x2000,
+			known: true,
+			out:   []Packet{},
+		}, {
+			// InactiveSource is meant for the TV, it's tracked nevertheless, but aborted because there
+			// is no handler for it.
+			name: "inactive_source",
+			in: []Packet{
+				{Playback2, Broadcast, OpActiveSource, []byte{0x20, 0x00}},
+				{Playback2, AudioSystem, OpInactiveSource, []byte{0x20, 0x00}},
+			},
+			known: false,
+			out: []Packet{
+				{AudioSystem, Playback2, OpFeatureAbort, []byte{byte(OpInactiveSource), byte(AbortUnrecognizedOpCode)}},
+			},
+		}, {
+			// Only the active source can become inactive.
+			name: "inactive_source_other_device",
+			in: []Packet{
+				{Playback2, Broadcast, OpActiveSource, []byte{0x20, 0x00}},
+				{Playback3, AudioSystem, OpInactiveSource, []byte{0x30, 0x00}},
+			},
+			addr:  0x2000,
+			known: true,
+			out: []Packet{
+				{AudioSystem, Playback3, OpFeatureAbort, []byte{byte(OpInactiveSource), byte(AbortUnrecognizedOpCode)}},
+			},
+		}, {
+			name: "routing_change",
+			in: []Packet{
+				{TV, Broadcast, OpRoutingChange, []byte{0x10, 0x00, 0x20, 0x00}},
+			},
+			addr:  0x2000,
+			known: true,
+			out:   []Packet{},
+		}, {
+			name: "routing_information",
+			in: []Packet{
+				{Unregistered, Broadcast, OpRoutingInformation, []byte{0x21, 0x00}},
+			},
+			addr:  0x2100,
+			known: true,
+			out:   []Packet{},
+		}, {
+			name: "set_stream_path",
+			in: []Packet{
+				{TV, Broadcast, OpSetStreamPath, []byte{0x20, 0x00}},
+			},
+			addr:  0x2000,
+			known: true,
+			out:   []Packet{},
+		}, {
+			// Without an ActiveSourceHandler, the request isn't answered.
+			name: "set_stream_path_to_us",
+			in: []Packet{
+				{TV, Broadcast, OpSetStreamPath, us.Bytes()},
+			},
+			addr:   us,
+			known:  true,
+			active: true,
+			out:    []Packet{},
+		}, {
+			name: "request_active_source",
+			in: []Packet{
+				{TV, Broadcast, OpSetStreamPath, us.Bytes()},
+				{TV, Broadcast, OpRequestActiveSource, nil},
+			},
+			addr:   us,
+			known:  true,
+			active: true,
+			out: []Packet{
+				{AudioSystem, Broadcast, OpActiveSource, us.Bytes()},
+			},
+		}, {
+			name: "request_active_source_not_active",
+			in: []Packet{
+				{Playback2, Broadcast, OpActiveSource, []byte{0x20, 0x00}},
+				{TV, Broadcast, OpRequestActiveSource, nil},
+			},
+			addr:  0x2000,
+			known: true,
+			out:   []Packet{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := fake.New(AudioSystem, DeviceTypeAudio)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+			addr, known := c.ActiveSource()
+			if known != test.known || (known && addr != test.addr) {
+				t.Errorf("Expected active source %s (known: %t), got %s (known: %t)", test.addr, test.known, addr, known)
+			}
+			if active := c.IsActiveSource(); active != test.active {
+				t.Errorf("Expected IsActiveSource to be %t, got %t", test.active, active)
+			}
+		})
+	}
+}
+
+func TestActiveSourceHandler(t *testing.T) {
+	us := fake.PhysicalAddress
+	tests := []struct {
+		name  string
+		setup func(c *Cec)
+		in    []Packet
+		out   []Packet
+	}{
+		{
+			name: "set_stream_path_to_us",
+			in: []Packet{
+				{TV, Broadcast, OpSetStreamPath, us.Bytes()},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpActiveSource, us.Bytes()},
+			},
+		}, {
+			name: "set_stream_path_elsewhere",
+			in: []Packet{
+				{TV, Broadcast, OpSetStreamPath, []byte{0x20, 0x00}},
+			},
+			out: []Packet{},
+		}, {
+			// A handler added before the ActiveSourceHandler takes precedence.
+			name: "taken_by_other_handler",
+			setup: func(c *Cec) {
+				taken := false
+				c.AddHandleFunc(func(x *Cec, msg Message) bool {
+					if _, ok := msg.Cmd.(SetStreamPath); ok && !taken {
+						taken = true
+						return true
+					}
+					return false
+				})
+			},
+			in: []Packet{
+				{TV, Broadcast, OpSetStreamPath, us.Bytes()},
+				{TV, Broadcast, OpSetStreamPath, us.Bytes()},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpActiveSource, us.Bytes()},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := fake.New(AudioSystem, DeviceTypeAudio)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			if test.setup != nil {
+				test.setup(c)
+			}
+			c.AddHandler(ActiveSourceHandler{})
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+		})
+	}
+}
+
+func TestActiveSource_Outgoing(t *testing.T) {
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test"})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	d.Run(nil, func() {
+		if err := c.OneTouchPlay(); err != nil {
+			t.Errorf("One Touch Play failed: %s", err)
+		}
+		if !c.IsActiveSource() {
+			t.Errorf("Expected to be the active source after One Touch Play.")
+		}
+		if err := c.Send(TV, InactiveSource{Addr: fake.PhysicalAddress}); err != nil {
+			t.Errorf("Failed to send: %s", err)
+		}
+		if _, known := c.ActiveSource(); known {
+			t.Errorf("Expected active source to be unknown after InactiveSource.")
+		}
+	})
+}
+
+func TestWatchActiveSource(t *testing.T) {
+	d := newCloser()
+	c, err := New(d, Config{OSDName: "test"})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	w := c.WatchActiveSource()
+	go c.Run()
+
+	d.in <- Packet{Playback2, Broadcast, OpActiveSource, []byte{0x20, 0x00}}
+	if _, ok := <-w; !ok {
+		t.Fatalf("Expected change notification, but channel was closed.")
+	}
+	if addr, _ := c.ActiveSource(); addr != 0x2000 {
+		t.Errorf("Expected active source %s, got %s", PhysicalAddress(0x2000), addr)
+	}
+
+	c.Close()
+	if _, ok := <-w; ok {
+		t.Errorf("Expected channel to be closed after Close.")
+	}
+}
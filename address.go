@@ -0,0 +1,38 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+// Claims a logical address for a device of type t and returns it.
+//
+// The candidate addresses for t are polled in the order defined by the CEC specification (e.g.
+// Playback1, Playback2, Playback3 for playback devices). The first address that isn't
+// acknowledged by another device is claimed. If all candidates are in use, the device falls back
+// to Unregistered.
+func ClaimLogicalAddress(dev AddressClaimer, t DeviceType) (LogicalAddr, error) {
+	for _, a := range logicalAddrCandidates[t] {
+		present, err := dev.PollLogicalAddress(a)
+		if err != nil {
+			return Unregistered, err
+		}
+		if present {
+			continue
+		}
+		if err := dev.ClaimLogicalAddress(a); err != nil {
+			return Unregistered, err
+		}
+		return a, nil
+	}
+	return Unregistered, dev.ClaimLogicalAddress(Unregistered)
+}
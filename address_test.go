@@ -0,0 +1,60 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestClaimLogicalAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      DeviceType
+		occupied []LogicalAddr
+		expected LogicalAddr
+	}{
+		{"playback_free", DeviceTypePlayback, nil, Playback1},
+		{"playback_first_occupied", DeviceTypePlayback, []LogicalAddr{Playback1}, Playback2},
+		{"playback_all_occupied", DeviceTypePlayback, []LogicalAddr{Playback1, Playback2, Playback3}, Unregistered},
+		{"playback_other_types_occupied", DeviceTypePlayback, []LogicalAddr{TV, AudioSystem, Rec1}, Playback1},
+		{"tv_occupied", DeviceTypeTV, []LogicalAddr{TV}, FreeUse},
+		{"recorder", DeviceTypeRec, []LogicalAddr{Rec1, Rec2}, Rec3},
+		{"tuner", DeviceTypeTuner, []LogicalAddr{Tuner1}, Tuner2},
+		{"audio", DeviceTypeAudio, nil, AudioSystem},
+		{"audio_occupied", DeviceTypeAudio, []LogicalAddr{AudioSystem}, Unregistered},
+		{"switch", DeviceTypeSwitch, nil, Unregistered},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := fake.New(Unregistered, test.typ)
+			d.SetOccupied(test.occupied...)
+			addr, err := ClaimLogicalAddress(d, test.typ)
+			if err != nil {
+				t.Fatalf("Failed to claim logical address: %s", err)
+			}
+			if addr != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, addr)
+			}
+			if addr := d.GetLogicalAddress(); addr != test.expected {
+				t.Errorf("Expected device to have logical address %s, got %s", test.expected, addr)
+			}
+		})
+	}
+}
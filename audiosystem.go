@@ -0,0 +1,265 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// How much a single volume key press changes the volume.
+const volumeStep = 1
+
+// An AudioOutput applies the audio state of an AudioSystemHandler, e.g. to an ALSA mixer or an
+// amplifier. If a method returns an error, the state is left unchanged.
+type AudioOutput interface {
+	// Sets the volume between 0 and 100.
+	SetVolume(volume int) error
+
+	// Mutes or unmutes the audio.
+	SetMuted(muted bool) error
+
+	// Turns system audio mode on or off. While system audio mode is on, the audio system plays the
+	// audio and the TV mutes its speakers.
+	SetSystemAudioMode(on bool) error
+}
+
+// The AudioSystemHandler implements System Audio Control for audio systems. It keeps track of
+// the volume, mute, and system audio mode and answers SystemAudioModeRequest, SetSystemAudioMode,
+// GiveSystemAudioModeStatus, GiveAudioStatus, and the volume and mute user controls.
+type AudioSystemHandler struct {
+	out AudioOutput
+
+	mtx     sync.Mutex
+	volume  int
+	muted   bool
+	mode    bool
+	pressed bool // Whether the last pressed key was an audio control.
+}
+
+// Creates a new AudioSystemHandler with the initial volume. System audio mode is off and the audio
+// isn't muted initially.
+func NewAudioSystemHandler(out AudioOutput, volume int) *AudioSystemHandler {
+	return &AudioSystemHandler{
+		out:    out,
+		volume: clampVolume(volume),
+	}
+}
+
+func clampVolume(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// Returns the current volume and whether the audio is muted.
+func (h *AudioSystemHandler) AudioStatus() (volume int, muted bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.volume, h.muted
+}
+
+// Returns true if system audio mode is on.
+func (h *AudioSystemHandler) SystemAudioMode() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.mode
+}
+
+// Turns system audio mode on or off, e.g. because the user pressed a button on the audio system,
+// and broadcasts the new mode if it changed.
+func (h *AudioSystemHandler) SetSystemAudioMode(x *Cec, on bool) error {
+	changed, err := h.setMode(on)
+	if err != nil || !changed {
+		return err
+	}
+	return x.Send(Broadcast, SetSystemAudioMode{On: on})
+}
+
+// Sets the volume and mute state, e.g. because the user turned the volume knob on the audio
+// system. If system audio mode is on, the new status is reported to the TV.
+func (h *AudioSystemHandler) SetAudioStatus(x *Cec, volume int, muted bool) error {
+	if err := h.setVolume(volume); err != nil {
+		return err
+	}
+	if err := h.setMuted(muted); err != nil {
+		return err
+	}
+	if !h.SystemAudioMode() {
+		return nil
+	}
+	return x.Send(TV, h.status())
+}
+
+func (h *AudioSystemHandler) status() ReportAudioStatus {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return ReportAudioStatus{Volume: h.volume, Muted: h.muted}
+}
+
+// Sets the system audio mode and returns true if it changed.
+func (h *AudioSystemHandler) setMode(on bool) (bool, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.mode == on {
+		return false, nil
+	}
+	if err := h.out.SetSystemAudioMode(on); err != nil {
+		return false, err
+	}
+	h.mode = on
+	return true, nil
+}
+
+func (h *AudioSystemHandler) setVolume(v int) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	v = clampVolume(v)
+	if h.volume == v {
+		return nil
+	}
+	if err := h.out.SetVolume(v); err != nil {
+		return err
+	}
+	h.volume = v
+	return nil
+}
+
+func (h *AudioSystemHandler) adjustVolume(delta int) error {
+	h.mtx.Lock()
+	v := h.volume + delta
+	h.mtx.Unlock()
+	return h.setVolume(v)
+}
+
+func (h *AudioSystemHandler) setMuted(muted bool) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.muted == muted {
+		return nil
+	}
+	if err := h.out.SetMuted(muted); err != nil {
+		return err
+	}
+	h.muted = muted
+	return nil
+}
+
+func (h *AudioSystemHandler) toggleMuted() error {
+	h.mtx.Lock()
+	muted := !h.muted
+	h.mtx.Unlock()
+	return h.setMuted(muted)
+}
+
+// Returns true if c is one of the user controls handled by the AudioSystemHandler.
+func isAudioControl(c UserControl) bool {
+	switch c {
+	case UcVolumeUp, UcVolumeDown, UcMute, UcMuteFunction, UcRestoreVolumeFunction:
+		return true
+	}
+	return false
+}
+
+// Remembers whether c is an audio control, so that its release is handled as well. Returns true
+// if it is.
+func (h *AudioSystemHandler) press(c UserControl) bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.pressed = isAudioControl(c)
+	return h.pressed
+}
+
+// Returns true if the last pressed key was an audio control.
+func (h *AudioSystemHandler) release() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	pressed := h.pressed
+	h.pressed = false
+	return pressed
+}
+
+// AudioSystemHandler implements Handler.
+func (h *AudioSystemHandler) HandleMessage(x *Cec, msg Message) bool {
+	switch cmd := msg.Cmd.(type) {
+	case SystemAudioModeRequest:
+		// A request with a physical address turns system audio mode on, a request without turns
+		// it off.
+		on := cmd.Addr != nil
+		if _, err := h.setMode(on); err != nil {
+			x.log.Warn("Failed to set system audio mode", append(messageAttrs(msg), slog.Any("error", err))...)
+			x.Reply(msg.Initiator, FeatureAbort{Abort: cmd.Op(), Reason: AbortRefused})
+			return true
+		}
+		x.Reply(Broadcast, SetSystemAudioMode{On: on})
+		return true
+
+	case SetSystemAudioMode:
+		if msg.Follower == Broadcast {
+			// Only audio systems broadcast their mode, there is nothing to do for us.
+			return false
+		}
+		changed, err := h.setMode(cmd.On)
+		if err != nil {
+			x.log.Warn("Failed to set system audio mode", append(messageAttrs(msg), slog.Any("error", err))...)
+			x.Reply(msg.Initiator, FeatureAbort{Abort: cmd.Op(), Reason: AbortRefused})
+			return true
+		}
+		if changed {
+			x.Reply(Broadcast, SetSystemAudioMode{On: cmd.On})
+		}
+		return true
+
+	case GiveSystemAudioModeStatus:
+		x.Reply(msg.Initiator, SystemAudioModeStatus{On: h.SystemAudioMode()})
+		return true
+
+	case GiveAudioStatus:
+		x.Reply(msg.Initiator, h.status())
+		return true
+
+	case UserControlPressed:
+		if !h.press(cmd.Pressed) {
+			return false
+		}
+		var err error
+		switch cmd.Pressed {
+		case UcVolumeUp:
+			err = h.adjustVolume(volumeStep)
+		case UcVolumeDown:
+			err = h.adjustVolume(-volumeStep)
+		case UcMute:
+			err = h.toggleMuted()
+		case UcMuteFunction:
+			err = h.setMuted(true)
+		case UcRestoreVolumeFunction:
+			err = h.setMuted(false)
+		}
+		if err != nil {
+			x.log.Warn("Failed to change audio status", append(messageAttrs(msg), slog.Any("error", err))...)
+		}
+		// The status is reported even on failure, so the initiator can display the actual state.
+		x.Reply(msg.Initiator, h.status())
+		return true
+
+	case UserControlReleased:
+		return h.release()
+	}
+	return false
+}
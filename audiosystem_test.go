@@ -0,0 +1,262 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+// An AudioOutput that records the applied state.
+type output struct {
+	volume int
+	muted  bool
+	mode   bool
+	err    error // Returned by all methods if set.
+}
+
+func (o *output) SetVolume(volume int) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.volume = volume
+	return nil
+}
+
+func (o *output) SetMuted(muted bool) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.muted = muted
+	return nil
+}
+
+func (o *output) SetSystemAudioMode(on bool) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.mode = on
+	return nil
+}
+
+func TestAudioSystemHandler(t *testing.T) {
+	tvAddr := RootAddress
+	tests := []struct {
+		name  string
+		setup func(o *output)
+		in    []Packet
+		out   []Packet
+		want  output
+	}{
+		{
+			name: "system_audio_mode_request_on",
+			in: []Packet{
+				{TV, AudioSystem, OpSystemAudioModeRequest, tvAddr.Bytes()},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpSetSystemAudioMode, []byte{0x01}},
+			},
+			want: output{volume: 20, mode: true},
+		}, {
+			name: "system_audio_mode_request_off",
+			in: []Packet{
+				{TV, AudioSystem, OpSystemAudioModeRequest, tvAddr.Bytes()},
+				{TV, AudioSystem, OpSystemAudioModeRequest, []byte{}},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpSetSystemAudioMode, []byte{0x01}},
+				{AudioSystem, Broadcast, OpSetSystemAudioMode, []byte{0x00}},
+			},
+			want: output{volume: 20, mode: false},
+		}, {
+			name:  "system_audio_mode_request_refused",
+			setup: func(o *output) { o.err = errors.New("fail") },
+			in: []Packet{
+				{TV, AudioSystem, OpSystemAudioModeRequest, tvAddr.Bytes()},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpFeatureAbort, []byte{byte(OpSystemAudioModeRequest), byte(AbortRefused)}},
+			},
+			want: output{volume: 20, err: errors.New("fail")},
+		}, {
+			name: "set_system_audio_mode",
+			in: []Packet{
+				{TV, AudioSystem, OpSetSystemAudioMode, []byte{0x01}},
+				// Unchanged, must not be broadcast again.
+				{TV, AudioSystem, OpSetSystemAudioMode, []byte{0x01}},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpSetSystemAudioMode, []byte{0x01}},
+			},
+			want: output{volume: 20, mode: true},
+		}, {
+			name: "give_system_audio_mode_status",
+			in: []Packet{
+				{TV, AudioSystem, OpGiveSystemAudioModeStatus, []byte{}},
+				{TV, AudioSystem, OpSystemAudioModeRequest, tvAddr.Bytes()},
+				{TV, AudioSystem, OpGiveSystemAudioModeStatus, []byte{}},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpSystemAudioModeStatus, []byte{0x00}},
+				{AudioSystem, Broadcast, OpSetSystemAudioMode, []byte{0x01}},
+				{AudioSystem, TV, OpSystemAudioModeStatus, []byte{0x01}},
+			},
+			want: output{volume: 20, mode: true},
+		}, {
+			name: "give_audio_status",
+			in: []Packet{
+				{TV, AudioSystem, OpGiveAudioStatus, []byte{}},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpReportAudioStatus, []byte{20}},
+			},
+			want: output{volume: 20},
+		}, {
+			name: "volume_keys",
+			in: []Packet{
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcVolumeUp)}},
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcVolumeUp)}},
+				{TV, AudioSystem, OpUserControlReleased, nil},
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcVolumeDown)}},
+				{TV, AudioSystem, OpUserControlReleased, nil},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpReportAudioStatus, []byte{21}},
+				{AudioSystem, TV, OpReportAudioStatus, []byte{22}},
+				{AudioSystem, TV, OpReportAudioStatus, []byte{21}},
+			},
+			want: output{volume: 21},
+		}, {
+			name: "mute_keys",
+			in: []Packet{
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcMute)}},
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcMute)}},
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcMuteFunction)}},
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcRestoreVolumeFunction)}},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpReportAudioStatus, []byte{0x80 | 20}},
+				{AudioSystem, TV, OpReportAudioStatus, []byte{20}},
+				{AudioSystem, TV, OpReportAudioStatus, []byte{0x80 | 20}},
+				{AudioSystem, TV, OpReportAudioStatus, []byte{20}},
+			},
+			want: output{volume: 20},
+		}, {
+			// The release belongs to the last pressed key, an operand sent by some devices is
+			// ignored.
+			name: "other_keys",
+			in: []Packet{
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcVolumeUp)}},
+				{TV, AudioSystem, OpUserControlPressed, []byte{byte(UcPlay)}},
+				{TV, AudioSystem, OpUserControlReleased, []byte{byte(UcVolumeUp)}},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpReportAudioStatus, []byte{21}},
+				{AudioSystem, TV, OpFeatureAbort, []byte{byte(OpUserControlPressed), byte(AbortUnrecognizedOpCode)}},
+				{AudioSystem, TV, OpFeatureAbort, []byte{byte(OpUserControlReleased), byte(AbortUnrecognizedOpCode)}},
+			},
+			want: output{volume: 21},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &output{volume: 20}
+			h := NewAudioSystemHandler(o, 20)
+			if test.setup != nil {
+				test.setup(o)
+			}
+			d := fake.New(AudioSystem, DeviceTypeAudio)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(h)
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+			if o.volume != test.want.volume || o.muted != test.want.muted || o.mode != test.want.mode {
+				t.Errorf("Expected output %+v, got %+v", test.want, *o)
+			}
+		})
+	}
+}
+
+func TestAudioSystemHandler_SetAudioStatus(t *testing.T) {
+	o := &output{}
+	h := NewAudioSystemHandler(o, 0)
+	d := fake.New(AudioSystem, DeviceTypeAudio)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	actual := d.Run(nil, func() {
+		// The TV is only informed while system audio mode is on.
+		if err := h.SetAudioStatus(c, 10, false); err != nil {
+			t.Errorf("SetAudioStatus failed: %s", err)
+		}
+		if err := h.SetSystemAudioMode(c, true); err != nil {
+			t.Errorf("SetSystemAudioMode failed: %s", err)
+		}
+		if err := h.SetAudioStatus(c, 30, true); err != nil {
+			t.Errorf("SetAudioStatus failed: %s", err)
+		}
+	})
+	expected := []Packet{
+		{AudioSystem, Broadcast, OpSetSystemAudioMode, []byte{0x01}},
+		{AudioSystem, TV, OpReportAudioStatus, []byte{0x80 | 30}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+	if volume, muted := h.AudioStatus(); volume != 30 || !muted {
+		t.Errorf("Expected volume 30 (muted), got %d (muted: %t)", volume, muted)
+	}
+}
+
+func TestAudioSystemHandler_VolumeLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		volume  int
+		pressed UserControl
+	}{
+		{"max", 100, UcVolumeUp},
+		{"min", 0, UcVolumeDown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewAudioSystemHandler(&output{}, test.volume)
+			d := fake.New(AudioSystem, DeviceTypeAudio)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(h)
+			in := []Packet{{TV, AudioSystem, OpUserControlPressed, []byte{byte(test.pressed)}}}
+			actual := d.Run(in, func() { c.Run() })
+			expected := []Packet{{AudioSystem, TV, OpReportAudioStatus, []byte{byte(test.volume)}}}
+			if diff := cmp.Diff(actual, expected); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+			}
+		})
+	}
+}
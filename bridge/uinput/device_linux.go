@@ -0,0 +1,149 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package uinput
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	iocWrite = 1
+
+	maxNameSize = 80 // UINPUT_MAX_NAME_SIZE
+	busVirtual  = 0x06
+)
+
+func ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | uintptr('U')<<8 | nr
+}
+
+var (
+	uiDevCreate  = ioc(0, 1, 0)
+	uiDevDestroy = ioc(0, 2, 0)
+	uiDevSetup   = ioc(iocWrite, 3, unsafe.Sizeof(uinputSetup{}))
+	uiSetEvBit   = ioc(iocWrite, 100, unsafe.Sizeof(int32(0)))
+	uiSetKeyBit  = ioc(iocWrite, 101, unsafe.Sizeof(int32(0)))
+)
+
+// struct input_id
+type inputID struct {
+	bustype uint16
+	vendor  uint16
+	product uint16
+	version uint16
+}
+
+// struct uinput_setup
+type uinputSetup struct {
+	id           inputID
+	name         [maxNameSize]byte
+	ffEffectsMax uint32
+}
+
+// struct input_event
+type inputEvent struct {
+	time  syscall.Timeval
+	typ   uint16
+	code  uint16
+	value int32
+}
+
+// Device is a virtual keyboard created via uinput.
+type Device struct {
+	fd int
+}
+
+// Creates a virtual keyboard with the given name via the uinput device at path (usually
+// DefaultPath) that can emit all keys in keymap.
+func Open(path, name string, keymap Keymap) (*Device, error) {
+	fd, err := syscall.Open(path, syscall.O_WRONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	d := &Device{fd: fd}
+	if err := d.setup(name, keymap); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Device) ioctl(name string, req, arg uintptr) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(d.fd), req, arg)
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		default:
+			return fmt.Errorf("%s: %w", name, errno)
+		}
+	}
+}
+
+func (d *Device) setup(name string, keymap Keymap) error {
+	if err := d.ioctl("UI_SET_EVBIT", uiSetEvBit, uintptr(EvKey)); err != nil {
+		return err
+	}
+	for _, c := range keymap.Codes() {
+		if err := d.ioctl("UI_SET_KEYBIT", uiSetKeyBit, uintptr(c)); err != nil {
+			return err
+		}
+	}
+	s := uinputSetup{id: inputID{bustype: busVirtual}}
+	copy(s.name[:maxNameSize-1], name)
+	if err := d.ioctl("UI_DEV_SETUP", uiDevSetup, uintptr(unsafe.Pointer(&s))); err != nil {
+		return err
+	}
+	return d.ioctl("UI_DEV_CREATE", uiDevCreate, 0)
+}
+
+// Device implements EventWriter.
+func (d *Device) WriteEvents(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	raw := make([]inputEvent, len(events))
+	for i, e := range events {
+		raw[i] = inputEvent{typ: e.Type, code: e.Code, value: e.Value}
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(&raw[0])), len(raw)*int(unsafe.Sizeof(raw[0])))
+	for len(buf) > 0 {
+		n, err := syscall.Write(d.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// Removes the virtual keyboard.
+func (d *Device) Close() error {
+	err := d.ioctl("UI_DEV_DESTROY", uiDevDestroy, 0)
+	if cerr := syscall.Close(d.fd); err == nil {
+		err = cerr
+	}
+	return err
+}
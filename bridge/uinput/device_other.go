@@ -0,0 +1,38 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package uinput
+
+import "errors"
+
+// Device is a virtual keyboard created via uinput.
+type Device struct{}
+
+// Creates a virtual keyboard. uinput is only available on linux.
+func Open(path, name string, keymap Keymap) (*Device, error) {
+	return nil, errors.New("uinput is only available on linux")
+}
+
+// Device implements EventWriter.
+func (d *Device) WriteEvents(events []Event) error {
+	return errors.New("uinput is only available on linux")
+}
+
+// Removes the virtual keyboard.
+func (d *Device) Close() error {
+	return nil
+}
@@ -0,0 +1,275 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package forwards remote control keys received via CEC to a Linux input device, e.g. to
+// control an application with the TV remote as if it was a keyboard.
+package uinput
+
+import (
+	"log/slog"
+	"sync"
+
+	"znkr.io/cec"
+)
+
+// The usual path of the uinput device.
+const DefaultPath = "/dev/uinput"
+
+// Event types and codes from <linux/input-event-codes.h>.
+const (
+	EvSyn     uint16 = 0x00
+	EvKey     uint16 = 0x01
+	SynReport uint16 = 0x00
+)
+
+// Values of EvKey events.
+const (
+	keyReleased int32 = 0
+	keyPressed  int32 = 1
+	keyRepeated int32 = 2
+)
+
+// Key codes from <linux/input-event-codes.h> used by the default keymap.
+const (
+	KeyEsc          uint16 = 1
+	Key1            uint16 = 2
+	Key2            uint16 = 3
+	Key3            uint16 = 4
+	Key4            uint16 = 5
+	Key5            uint16 = 6
+	Key6            uint16 = 7
+	Key7            uint16 = 8
+	Key8            uint16 = 9
+	Key9            uint16 = 10
+	Key0            uint16 = 11
+	KeyBackspace    uint16 = 14
+	KeyEnter        uint16 = 28
+	KeyDot          uint16 = 52
+	KeyF5           uint16 = 63
+	KeyUp           uint16 = 103
+	KeyPageUp       uint16 = 104
+	KeyLeft         uint16 = 105
+	KeyRight        uint16 = 106
+	KeyDown         uint16 = 108
+	KeyPageDown     uint16 = 109
+	KeyMute         uint16 = 113
+	KeyVolumeDown   uint16 = 114
+	KeyVolumeUp     uint16 = 115
+	KeyPower        uint16 = 116
+	KeyHelp         uint16 = 138
+	KeyMenu         uint16 = 139
+	KeySetup        uint16 = 141
+	KeyEjectCD      uint16 = 161
+	KeyNextSong     uint16 = 163
+	KeyPreviousSong uint16 = 165
+	KeyStopCD       uint16 = 166
+	KeyRecord       uint16 = 167
+	KeyRewind       uint16 = 168
+	KeyPauseCD      uint16 = 201
+	KeyPlay         uint16 = 207
+	KeyFastForward  uint16 = 208
+	KeyInfo         uint16 = 0x166
+	KeyEPG          uint16 = 0x16d
+	KeyRed          uint16 = 0x18e
+	KeyGreen        uint16 = 0x18f
+	KeyYellow       uint16 = 0x190
+	KeyBlue         uint16 = 0x191
+	KeyChannelUp    uint16 = 0x192
+	KeyChannelDown  uint16 = 0x193
+	KeyLast         uint16 = 0x195
+)
+
+// Event mirrors struct input_event from <linux/input.h>. The timestamp is omitted, it's set by the
+// kernel.
+type Event struct {
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// An EventWriter writes input events to an input device.
+type EventWriter interface {
+	// Writes events to the device. The events are written at once, a sequence of events is
+	// terminated by a SynReport.
+	WriteEvents(events []Event) error
+}
+
+// A Keymap maps CEC user controls to Linux key codes.
+type Keymap map[cec.UserControl]uint16
+
+// Returns the default keymap. The keymap is a new copy that can be modified.
+func DefaultKeymap() Keymap {
+	return Keymap{
+		cec.UcSelect:             KeyEnter,
+		cec.UcUp:                 KeyUp,
+		cec.UcDown:               KeyDown,
+		cec.UcLeft:               KeyLeft,
+		cec.UcRight:              KeyRight,
+		cec.UcRootMenu:           KeyMenu,
+		cec.UcSetupMenu:          KeySetup,
+		cec.UcExit:               KeyEsc,
+		cec.UcNumber0:            Key0,
+		cec.UcNumber1:            Key1,
+		cec.UcNumber2:            Key2,
+		cec.UcNumber3:            Key3,
+		cec.UcNumber4:            Key4,
+		cec.UcNumber5:            Key5,
+		cec.UcNumber6:            Key6,
+		cec.UcNumber7:            Key7,
+		cec.UcNumber8:            Key8,
+		cec.UcNumber9:            Key9,
+		cec.UcDot:                KeyDot,
+		cec.UcEnter:              KeyEnter,
+		cec.UcClear:              KeyBackspace,
+		cec.UcChannelUp:          KeyChannelUp,
+		cec.UcChannelDown:        KeyChannelDown,
+		cec.UcPreviousChannel:    KeyLast,
+		cec.UcDisplayInformation: KeyInfo,
+		cec.UcHelp:               KeyHelp,
+		cec.UcPageUp:             KeyPageUp,
+		cec.UcPageDown:           KeyPageDown,
+		cec.UcPower:              KeyPower,
+		cec.UcVolumeUp:           KeyVolumeUp,
+		cec.UcVolumeDown:         KeyVolumeDown,
+		cec.UcMute:               KeyMute,
+		cec.UcPlay:               KeyPlay,
+		cec.UcStop:               KeyStopCD,
+		cec.UcPause:              KeyPauseCD,
+		cec.UcRecord:             KeyRecord,
+		cec.UcRewind:             KeyRewind,
+		cec.UcFastForward:        KeyFastForward,
+		cec.UcEject:              KeyEjectCD,
+		cec.UcForward:            KeyNextSong,
+		cec.UcBackward:           KeyPreviousSong,
+		cec.UcEPG:                KeyEPG,
+		cec.UcF1Blue:             KeyBlue,
+		cec.UcF2Red:              KeyRed,
+		cec.UcF3Green:            KeyGreen,
+		cec.UcF4Yellow:           KeyYellow,
+		cec.UcF5:                 KeyF5,
+	}
+}
+
+// Returns the key codes of all keys in the keymap.
+func (m Keymap) Codes() []uint16 {
+	seen := make(map[uint16]bool)
+	var codes []uint16
+	for _, c := range m {
+		if !seen[c] {
+			seen[c] = true
+			codes = append(codes, c)
+		}
+	}
+	return codes
+}
+
+// Configuration for a Bridge.
+type Config struct {
+	Keymap Keymap       // The keymap to use, DefaultKeymap() is used if nil.
+	Clock  cec.Clock    // The clock for key timing, cec.SystemClock is used if nil.
+	Logger *slog.Logger // Logger for diagnostics, slog.Default() is used if nil.
+}
+
+// A Bridge is a cec.Handler that writes the keys pressed on a remote control to an EventWriter.
+// Keys that aren't part of the keymap are passed on to other handlers.
+type Bridge struct {
+	w      EventWriter
+	keymap Keymap
+	keys   *cec.KeyHandler
+	log    *slog.Logger
+
+	mtx    sync.Mutex
+	mapped bool // Whether the last pressed key is part of the keymap.
+}
+
+// Creates a new Bridge writing to w.
+func New(w EventWriter, c Config) *Bridge {
+	b := &Bridge{
+		w:      w,
+		keymap: c.Keymap,
+		log:    c.Logger,
+	}
+	if b.keymap == nil {
+		b.keymap = DefaultKeymap()
+	}
+	if b.log == nil {
+		b.log = slog.Default()
+	}
+	b.keys = cec.NewKeyHandler(c.Clock, b.keyEvent)
+	return b
+}
+
+// Bridge implements cec.Handler.
+func (b *Bridge) HandleMessage(x *cec.Cec, msg cec.Message) bool {
+	switch cmd := msg.Cmd.(type) {
+	case cec.UserControlPressed:
+		_, mapped := b.keymap[cmd.Pressed]
+		held := b.setMapped(mapped)
+		if mapped {
+			return b.keys.HandleMessage(x, msg)
+		}
+		if held {
+			// Pressing another key releases the held key.
+			b.keys.HandleMessage(x, cec.Message{
+				Initiator: msg.Initiator,
+				Follower:  msg.Follower,
+				Cmd:       cec.UserControlReleased{},
+			})
+		}
+		return false
+
+	case cec.UserControlReleased:
+		// A release ends the last pressed key, it's only handled if that key is mapped.
+		if !b.setMapped(false) {
+			return false
+		}
+		return b.keys.HandleMessage(x, msg)
+	}
+	return false
+}
+
+// Sets whether the last pressed key is mapped and returns the previous value.
+func (b *Bridge) setMapped(mapped bool) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	prev := b.mapped
+	b.mapped = mapped
+	return prev
+}
+
+func (b *Bridge) keyEvent(e cec.KeyEvent) {
+	code, ok := b.keymap[e.Key]
+	if !ok {
+		return
+	}
+	var value int32
+	switch e.Type {
+	case cec.KeyDown:
+		value = keyPressed
+	case cec.KeyRepeat:
+		value = keyRepeated
+	case cec.KeyUp:
+		value = keyReleased
+	}
+	err := b.w.WriteEvents([]Event{
+		{Type: EvKey, Code: code, Value: value},
+		{Type: EvSyn, Code: SynReport, Value: 0},
+	})
+	if err != nil {
+		b.log.Warn("Failed to write input event",
+			slog.String("key", e.Key.String()),
+			slog.String("type", e.Type.String()),
+			slog.Any("error", err))
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uinput
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec"
+	"znkr.io/cec/internal/fakeclock"
+)
+
+// Records all written events.
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) WriteEvents(events []Event) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func TestBridge(t *testing.T) {
+	pressed := func(k cec.UserControl) cec.Message {
+		return cec.Message{Initiator: cec.TV, Follower: cec.Playback1, Cmd: cec.UserControlPressed{Pressed: k}}
+	}
+	released := cec.Message{Initiator: cec.TV, Follower: cec.Playback1, Cmd: cec.UserControlReleased{}}
+	key := func(code uint16, value int32) []Event {
+		return []Event{{EvKey, code, value}, {EvSyn, SynReport, 0}}
+	}
+	concat := func(events ...[]Event) []Event {
+		var all []Event
+		for _, e := range events {
+			all = append(all, e...)
+		}
+		return all
+	}
+
+	tests := []struct {
+		name    string
+		keymap  Keymap
+		in      []cec.Message
+		advance time.Duration // Advanced after each message.
+		handled []bool
+		want    []Event
+	}{
+		{
+			name:    "press_release",
+			in:      []cec.Message{pressed(cec.UcSelect), released},
+			handled: []bool{true, true},
+			want:    concat(key(KeyEnter, keyPressed), key(KeyEnter, keyReleased)),
+		}, {
+			name:    "hold",
+			in:      []cec.Message{pressed(cec.UcUp), pressed(cec.UcUp), pressed(cec.UcUp), released},
+			advance: 450 * time.Millisecond,
+			handled: []bool{true, true, true, true},
+			want: concat(
+				key(KeyUp, keyPressed),
+				key(KeyUp, keyRepeated),
+				key(KeyUp, keyRepeated),
+				key(KeyUp, keyReleased)),
+		}, {
+			name:    "release_timeout",
+			in:      []cec.Message{pressed(cec.UcDown)},
+			advance: time.Second,
+			handled: []bool{true},
+			want:    concat(key(KeyDown, keyPressed), key(KeyDown, keyReleased)),
+		}, {
+			name:    "custom_keymap",
+			keymap:  Keymap{cec.UcSelect: KeyPlay},
+			in:      []cec.Message{pressed(cec.UcSelect), released},
+			handled: []bool{true, true},
+			want:    concat(key(KeyPlay, keyPressed), key(KeyPlay, keyReleased)),
+		}, {
+			// Unmapped keys are left to other handlers.
+			name:    "unmapped",
+			keymap:  Keymap{cec.UcSelect: KeyEnter},
+			in:      []cec.Message{pressed(cec.UcVolumeUp), released},
+			handled: []bool{false, false},
+			want:    nil,
+		}, {
+			// Pressing an unmapped key releases the held mapped key, the release of the unmapped key
+			// is left to other handlers.
+			name:    "unmapped_while_held",
+			keymap:  Keymap{cec.UcSelect: KeyEnter},
+			in:      []cec.Message{pressed(cec.UcSelect), pressed(cec.UcVolumeUp), released},
+			handled: []bool{true, false, false},
+			want:    concat(key(KeyEnter, keyPressed), key(KeyEnter, keyReleased)),
+		}, {
+			// A release ends the last pressed key, a second release is left to other handlers.
+			name:    "double_release",
+			keymap:  Keymap{cec.UcSelect: KeyEnter},
+			in:      []cec.Message{pressed(cec.UcSelect), released, released},
+			handled: []bool{true, true, false},
+			want:    concat(key(KeyEnter, keyPressed), key(KeyEnter, keyReleased)),
+		}, {
+			name:    "other_messages",
+			in:      []cec.Message{{Initiator: cec.TV, Follower: cec.Playback1, Cmd: cec.GiveOSDName{}}},
+			handled: []bool{false},
+			want:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := fakeclock.New()
+			r := &recorder{}
+			b := New(r, Config{
+				Keymap: test.keymap,
+				Clock:  clock,
+				Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			})
+			for i, msg := range test.in {
+				if handled := b.HandleMessage(nil, msg); handled != test.handled[i] {
+					t.Errorf("Expected HandleMessage(%v) = %t, got %t", msg, test.handled[i], handled)
+				}
+				clock.Advance(test.advance)
+			}
+			if diff := cmp.Diff(r.events, test.want); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.want, r.events, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultKeymap(t *testing.T) {
+	m := DefaultKeymap()
+	m[cec.UcSelect] = KeyPlay
+	if DefaultKeymap()[cec.UcSelect] != KeyEnter {
+		t.Errorf("Modifying the default keymap modified later copies")
+	}
+	// UcSelect and UcEnter both map to KeyEnter.
+	if got, want := len(DefaultKeymap().Codes()), len(DefaultKeymap())-1; got != want {
+		t.Errorf("Expected %d codes, got %d", want, got)
+	}
+}
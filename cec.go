@@ -16,24 +16,53 @@
 package cec
 
 import (
+	"context"
+	"io"
 	"log"
+	"log/slog"
+	"sync"
+	"time"
 )
 
 const cecVersion = 0x04 // CEC 1.3a
 
 // Configuration for this CEC endpoint.
 type Config struct {
-	OSDName string // Name to display in OSD menus, must be between 1 and 14 ASCII characters.
+	OSDName string       // Name to display in OSD menus, must be between 1 and 14 ASCII characters.
+	Logger  *slog.Logger // Logger for diagnostics, slog.Default() is used if nil.
+
+	// The ISO 639-2 code of the menu language of this device, e.g. "eng". If empty, requests for
+	// the menu language aren't answered by the DefaultHandler.
+	MenuLanguage string
+
+	// Called from Run whenever the TV broadcasts a new menu language, may be nil. The menu
+	// language of this device follows the TV.
+	OnMenuLanguage func(language string)
 }
 
 // Main type to communicate with the CEC bus.
 type Cec struct {
 	dev      Device
 	osd      string
+	log      *slog.Logger
 	handlers []Handler
+	spyMtx   sync.RWMutex
 	spy      chan<- Message
 	spyDone  <-chan struct{}
-	started  bool
+	started  bool // Guarded by mtx.
+
+	closeOnce sync.Once
+	closing   chan struct{} // Closed when Close is called.
+	stopped   chan struct{} // Closed when RunContext returned.
+
+	mtx      sync.Mutex
+	requests []*request // Pending requests, see Request.
+
+	active activeSourceState
+	remote remoteCache
+
+	lang   string // Guarded by mtx.
+	onLang func(language string)
 }
 
 // Creates a new Cec object using dev to communicate with the hardware.
@@ -41,13 +70,39 @@ func New(dev Device, c Config) (*Cec, error) {
 	if !isValidOsdName(c.OSDName) {
 		return nil, InvalidOSDName{}
 	}
+	if c.MenuLanguage != "" && !isValidLanguage(c.MenuLanguage) {
+		return nil, InvalidLanguage{c.MenuLanguage}
+	}
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &Cec{
 		dev:      dev,
 		osd:      c.OSDName,
+		log:      logger,
 		handlers: []Handler{},
+		closing:  make(chan struct{}),
+		stopped:  make(chan struct{}),
+		lang:     c.MenuLanguage,
+		onLang:   c.OnMenuLanguage,
 	}, nil
 }
 
+// Returns the logging attributes describing a packet.
+func packetAttrs(initiator, follower LogicalAddr, op OpCode) []any {
+	return []any{
+		slog.String("initiator", initiator.String()),
+		slog.String("follower", follower.String()),
+		slog.String("opcode", op.String()),
+	}
+}
+
+// Returns the logging attributes describing a message.
+func messageAttrs(msg Message) []any {
+	return packetAttrs(msg.Initiator, msg.Follower, msg.Cmd.Op())
+}
+
 // A Handler for HDMI CEC messages.
 type Handler interface {
 	// Handles a message and returns true if the message was handled. Once a message is handled, it
@@ -70,13 +125,13 @@ type UnhandledHandler struct{}
 func (f UnhandledHandler) HandleMessage(x *Cec, msg Message) bool {
 	if msg.Initiator == Unregistered {
 		// Ignore messages ending up here that are send from Unregistered.
-		log.Printf("Unexpected message from unregistered initiator: %s", msg)
+		x.log.Info("Unexpected message from unregistered initiator", messageAttrs(msg)...)
 		return true
 	}
 
 	switch msg.Cmd.(type) {
 	case FeatureAbort:
-		log.Printf("Unexpected feature abort: %s", msg)
+		x.log.Info("Unexpected feature abort", messageAttrs(msg)...)
 		return true
 
 	case Standby:
@@ -88,7 +143,7 @@ func (f UnhandledHandler) HandleMessage(x *Cec, msg Message) bool {
 	// Send FeatureAbort if this message was directly addressed to us. Unhandled broadcasts are
 	// ignored.
 	if msg.Follower != Broadcast {
-		log.Printf("Unexpected message: %s", msg)
+		x.log.Info("Unexpected message", messageAttrs(msg)...)
 		x.Reply(msg.Initiator, FeatureAbort{
 			Abort:  msg.Cmd.Op(),
 			Reason: AbortUnrecognizedOpCode,
@@ -99,7 +154,7 @@ func (f UnhandledHandler) HandleMessage(x *Cec, msg Message) bool {
 }
 
 // The DefaultHandler handles a set of standard messages. The handles messages are for physical
-// address, vendor id, and CEC version.
+// address, vendor id, CEC version, and menu language.
 type DefaultHandler struct{}
 
 // DefaultHandler implements Handler.
@@ -129,6 +184,16 @@ func (h DefaultHandler) HandleMessage(x *Cec, msg Message) bool {
 			Version: cecVersion,
 		})
 		return true
+
+	case GetMenuLanguage:
+		lang := x.MenuLanguage()
+		if lang == "" {
+			return false
+		}
+		x.Reply(Broadcast, SetMenuLanguage{
+			Language: lang,
+		})
+		return true
 	}
 	return false
 }
@@ -136,6 +201,8 @@ func (h DefaultHandler) HandleMessage(x *Cec, msg Message) bool {
 // Adds a handler. If more than one handler is added all handlers are tried until one handler
 // returns true. May only be called before Start() was called.
 func (x *Cec) AddHandler(h Handler) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
 	if x.started {
 		log.Panic("Already started.")
 	}
@@ -174,9 +241,13 @@ func spy(l Listener) (chan<- Message, <-chan struct{}) {
 
 // Sets the listener. May only be called once before Start() was called.
 func (x *Cec) SetListener(l Listener) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
 	if x.started {
 		log.Panic("Already started.")
 	}
+	x.spyMtx.Lock()
+	defer x.spyMtx.Unlock()
 	if x.spy != nil {
 		log.Panic("Listener already set.")
 	}
@@ -188,118 +259,219 @@ func (x *Cec) SetListenerFunc(f func(msg Message)) {
 	x.SetListener(ListenerFunc(f))
 }
 
-func (x *Cec) spyIncoming(msg Message) {
+// Sends msg to the listener, if there is one. Messages sent after the listener was closed are
+// dropped.
+func (x *Cec) spyMessage(msg Message) {
+	x.spyMtx.RLock()
+	defer x.spyMtx.RUnlock()
 	if x.spy != nil {
 		x.spy <- msg
 	}
 }
 
-func (x *Cec) spyIncomingError(p Packet) {
-	if x.spy != nil {
-		x.spy <- Message{
-			Initiator: p.Initiator,
-			Follower:  p.Follower,
-			Cmd:       MakeUnknownCmd(p.Op, p.Data),
-		}
+// Closes the listener and waits until it received all pending messages.
+func (x *Cec) closeSpy() {
+	x.spyMtx.Lock()
+	spy := x.spy
+	x.spy = nil
+	x.spyMtx.Unlock()
+	if spy != nil {
+		close(spy)
+		<-x.spyDone
 	}
 }
 
-// Starts receiving and handling CEC messages.
+func (x *Cec) spyIncoming(msg Message) {
+	x.spyMessage(msg)
+}
+
+func (x *Cec) spyIncomingError(p Packet) {
+	x.spyMessage(Message{
+		Initiator: p.Initiator,
+		Follower:  p.Follower,
+		Cmd:       MakeUnknownCmd(p.Op, p.Data),
+	})
+}
+
+// Starts receiving and handling CEC messages. Run returns when the device stops delivering
+// messages or when the Cec is closed.
 func (x *Cec) Run() {
+	x.RunContext(context.Background())
+}
+
+// Starts receiving and handling CEC messages until ctx is done, the Cec is closed, or the device
+// stops delivering messages. The returned error describes why RunContext returned: ctx.Err(),
+// Closed, or DeviceClosed. Before RunContext returns, all pending messages are passed to the
+// listener.
+func (x *Cec) RunContext(ctx context.Context) error {
+	x.mtx.Lock()
 	if x.started {
+		x.mtx.Unlock()
 		log.Panic("Already started.")
 	}
 	x.started = true
-	unhandledHandler := UnhandledHandler{}
-	for p := range x.dev.Receive() {
-		msg, err := UnmarshalMessage(p)
-		if err != nil {
-			x.spyIncomingError(p)
-			log.Printf("Unable to unmarshal message %s: %s", p, err)
-			if p.Follower != Broadcast && p.Initiator != Unregistered {
-				// Signal the initiator that acting upon the received packet is not possible.
-				x.Reply(p.Initiator, FeatureAbort{
-					Abort:  p.Op,
-					Reason: AbortInvalidOperand,
-				})
+	x.mtx.Unlock()
+	defer close(x.stopped)
+	defer x.closeSpy()
+	defer x.active.close()
+
+	for {
+		select {
+		case p, ok := <-x.dev.Receive():
+			if !ok {
+				return DeviceClosed{}
 			}
-			continue
+			x.handle(p)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-x.closing:
+			return Closed{}
 		}
-		x.spyIncoming(msg)
-
-		// Some messages need to be ignored according to the spec.
-		flags, ok := getOpCodeFlags(msg.Cmd.Op())
-		if !ok {
-			// We don't know anything about this opcode.
-			log.Printf("Received message with unkown opcode: %s", msg)
-			if p.Follower != Broadcast && p.Initiator != Unregistered {
-				x.Reply(p.Initiator, FeatureAbort{
-					Abort:  p.Op,
-					Reason: AbortUnrecognizedOpCode,
-				})
-			}
-			continue
-		} else if msg.Follower == Broadcast && (flags&fBroadcast) == 0 {
-			// Message is not valid in broadcast mode, but was broadcast.
-			log.Printf("Received bradcast message which should be direct: %s", msg)
-			continue
-		} else if msg.Follower != Broadcast && (flags&fDirect) == 0 {
-			// Message is not valid in direct mode, but directly addressed.
-			log.Printf("Received direct message which should be a broadcast: %s", msg)
-			continue
-		} else if msg.Initiator == Unregistered && msg.Cmd.Op() != OpStandby &&
-			(flags&(fBroadcastResponse|fSwitchMessage) == 0) {
-			// Initiator is unregistered, ignore all messages except standby, switch messages and messages
-			// answered by a broadcast response.
-			continue
+	}
+}
+
+// Stops handling CEC messages, waits until Run or RunContext returned, and flushes the listener.
+// If the device implements io.Closer, it is closed as well. After Close returns, Send and Reply
+// fail with Closed. Close must not be called from within a Handler.
+func (x *Cec) Close() error {
+	err := error(Closed{})
+	x.closeOnce.Do(func() {
+		x.mtx.Lock()
+		close(x.closing)
+		started := x.started
+		x.mtx.Unlock()
+		if started {
+			<-x.stopped
+		}
+		x.closeSpy()
+		x.active.close()
+		err = nil
+		if c, ok := x.dev.(io.Closer); ok {
+			err = c.Close()
 		}
+	})
+	return err
+}
 
-		// Dispatch incoming message to handlers.
-		handled := false
-		for _, h := range x.handlers {
-			if h.HandleMessage(x, msg) {
-				handled = true
-				break
-			}
+// Returns true if the Cec was closed.
+func (x *Cec) isClosed() bool {
+	select {
+	case <-x.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (x *Cec) handle(p Packet) {
+	unhandledHandler := UnhandledHandler{}
+	msg, err := UnmarshalMessage(p)
+	if err != nil {
+		x.spyIncomingError(p)
+		x.log.Warn("Unable to unmarshal message",
+			append(packetAttrs(p.Initiator, p.Follower, p.Op), slog.Any("error", err))...)
+		if p.Follower != Broadcast && p.Initiator != Unregistered {
+			// Signal the initiator that acting upon the received packet is not possible.
+			x.Reply(p.Initiator, FeatureAbort{
+				Abort:  p.Op,
+				Reason: AbortInvalidOperand,
+			})
 		}
-		if !handled {
-			unhandledHandler.HandleMessage(x, msg)
+		return
+	}
+	x.spyIncoming(msg)
+
+	// Some messages need to be ignored according to the spec.
+	flags, ok := getOpCodeFlags(msg.Cmd.Op())
+	if !ok {
+		// We don't know anything about this opcode.
+		x.log.Warn("Received message with unknown opcode", messageAttrs(msg)...)
+		if p.Follower != Broadcast && p.Initiator != Unregistered {
+			x.Reply(p.Initiator, FeatureAbort{
+				Abort:  p.Op,
+				Reason: AbortUnrecognizedOpCode,
+			})
 		}
+		return
+	} else if msg.Follower == Broadcast && (flags&fBroadcast) == 0 {
+		// Message is not valid in broadcast mode, but was broadcast.
+		x.log.Warn("Received broadcast message which should be direct", messageAttrs(msg)...)
+		return
+	} else if msg.Follower != Broadcast && (flags&fDirect) == 0 {
+		// Message is not valid in direct mode, but directly addressed.
+		x.log.Warn("Received direct message which should be a broadcast", messageAttrs(msg)...)
+		return
+	} else if msg.Initiator == Unregistered && msg.Cmd.Op() != OpStandby &&
+		(flags&(fBroadcastResponse|fSwitchMessage) == 0) {
+		// Initiator is unregistered, ignore all messages except standby, switch messages and messages
+		// answered by a broadcast response.
+		return
 	}
-	if x.spy != nil {
-		close(x.spy)
-		<-x.spyDone
+
+	// The state of remote devices and the active source are tracked for all messages, responses
+	// to pending requests are consumed by the request.
+	x.remote.observe(msg, time.Now())
+	x.trackMenuLanguage(msg)
+	if x.trackActiveSource(msg) || x.dispatchResponse(msg) {
+		return
 	}
-}
 
-func (x *Cec) spyOutgoing(follower LogicalAddr, cmd Command) {
-	if x.spy != nil {
-		x.spy <- Message{
-			Initiator: x.dev.GetLogicalAddress(),
-			Follower:  follower,
-			Cmd:       cmd,
+	// Dispatch incoming message to handlers.
+	for _, h := range x.handlers {
+		if h.HandleMessage(x, msg) {
+			return
 		}
 	}
+	unhandledHandler.HandleMessage(x, msg)
 }
 
-// Sends cmd to follower.
+func (x *Cec) spyOutgoing(follower LogicalAddr, cmd Command) {
+	x.spyMessage(Message{
+		Initiator: x.dev.GetLogicalAddress(),
+		Follower:  follower,
+		Cmd:       cmd,
+	})
+}
+
+// Sends cmd to follower. If the device reports transmit results (see StatusDevice), a failed
+// transmission is returned as a TransmitError.
 func (x *Cec) Send(follower LogicalAddr, cmd Command) error {
+	if x.isClosed() {
+		return Closed{}
+	}
 	data, err := cmd.Marshal()
 	if err != nil {
 		return err
 	}
 	x.spyOutgoing(follower, cmd)
-	x.dev.Send(follower, cmd.Op(), data)
-	return nil
+	if d, ok := x.dev.(StatusDevice); ok {
+		err = d.SendStatus(follower, cmd.Op(), data)
+	} else {
+		x.dev.Send(follower, cmd.Op(), data)
+	}
+	if err == nil {
+		x.trackOutgoing(cmd)
+	}
+	return err
 }
 
 // Sends cmd to follower as a reply.
 func (x *Cec) Reply(follower LogicalAddr, cmd Command) error {
+	if x.isClosed() {
+		return Closed{}
+	}
 	data, err := cmd.Marshal()
 	if err != nil {
 		return err
 	}
 	x.spyOutgoing(follower, cmd)
-	x.dev.Reply(follower, cmd.Op(), data)
-	return nil
+	if d, ok := x.dev.(StatusDevice); ok {
+		err = d.ReplyStatus(follower, cmd.Op(), data)
+	} else {
+		x.dev.Reply(follower, cmd.Op(), data)
+	}
+	if err == nil {
+		x.trackOutgoing(cmd)
+	}
+	return err
 }
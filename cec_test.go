@@ -15,6 +15,11 @@
 package cec_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -25,6 +30,9 @@ import (
 
 var cmpOptions = cmp.AllowUnexported(GivePhysicalAddress{}, UnkownCmd{})
 
+// A logger that drops all records, used to keep the test output readable.
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
 func TestCec(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -125,6 +133,15 @@ func TestCec(t *testing.T) {
 			out: []Packet{
 				{AudioSystem, TV, OpStandby, []byte{}},
 			},
+		}, {
+			name: "show_on_tv",
+			setup: func(c *Cec) {
+				c.ShowOnTV("Hello", DisplayDefaultTime)
+			},
+			in: []Packet{},
+			out: []Packet{
+				{AudioSystem, TV, OpSetOSDString, append([]byte{0x00}, "Hello"...)},
+			},
 		},
 
 		// Tests without any handlers
@@ -240,6 +257,7 @@ func TestCec(t *testing.T) {
 			d := fake.New(AudioSystem, DeviceTypeAudio)
 			c, err := New(d, Config{
 				OSDName: "test",
+				Logger:  discard,
 			})
 			if err != nil {
 				t.Errorf("Error setting up %s", err)
@@ -301,6 +319,7 @@ func TestListener(t *testing.T) {
 			d := fake.New(AudioSystem, DeviceTypeAudio)
 			c, err := New(d, Config{
 				OSDName: "test",
+				Logger:  discard,
 			})
 			if err != nil {
 				t.Errorf("Error setting up %s", err)
@@ -331,3 +350,172 @@ func TestInvalidOsdName(t *testing.T) {
 		t.Errorf("Expected failure due to invalid OSD name, but succeeded.")
 	}
 }
+
+func TestSend_TransmitStatus(t *testing.T) {
+	statuses := []TxStatus{TxAck, TxNack, TxArbitrationLost, TxLowDrive, TxTimeout, TxError}
+	for _, s := range statuses {
+		t.Run(s.String(), func(t *testing.T) {
+			d := fake.New(AudioSystem, DeviceTypeAudio)
+			d.SetTxStatus(TV, s)
+			c, err := New(d, Config{
+				OSDName: "test",
+			})
+			if err != nil {
+				t.Errorf("Error setting up %s", err)
+				return
+			}
+
+			var expected error
+			if s != TxAck {
+				expected = TransmitError{Status: s}
+			}
+			d.Run(nil, func() {
+				if err := c.Send(TV, Standby{}); err != expected {
+					t.Errorf("Expected %v, got %v", expected, err)
+				}
+				if err := c.Reply(TV, Standby{}); err != expected {
+					t.Errorf("Expected %v, got %v", expected, err)
+				}
+			})
+		})
+	}
+}
+
+// closer is a device with a receive channel controlled by the test that records whether it was
+// closed.
+type closer struct {
+	Device
+	in     chan Packet
+	closed bool
+}
+
+func newCloser() *closer {
+	return &closer{
+		Device: fake.New(AudioSystem, DeviceTypeAudio),
+		in:     make(chan Packet),
+	}
+}
+
+func (d *closer) Receive() <-chan Packet {
+	return d.in
+}
+
+func (d *closer) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestRunContext_Cancel(t *testing.T) {
+	c, err := New(newCloser(), Config{OSDName: "test"})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.RunContext(ctx); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestRunContext_DeviceClosed(t *testing.T) {
+	d := newCloser()
+	c, err := New(d, Config{OSDName: "test"})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	close(d.in)
+	if err := c.RunContext(context.Background()); err != (DeviceClosed{}) {
+		t.Errorf("Expected %v, got %v", DeviceClosed{}, err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	d := newCloser()
+	c, err := New(d, Config{OSDName: "test"})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	var received []Message
+	c.SetListenerFunc(func(msg Message) {
+		received = append(received, msg)
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- c.RunContext(context.Background())
+	}()
+	if err := c.Send(TV, Standby{}); err != nil {
+		t.Errorf("Failed to send: %s", err)
+	}
+	d.in <- Packet{TV, AudioSystem, OpStandby, nil}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Failed to close: %s", err)
+	}
+	if err := <-done; err != (Closed{}) {
+		t.Errorf("Expected RunContext to return %v, got %v", Closed{}, err)
+	}
+	if !d.closed {
+		t.Errorf("Expected device to be closed.")
+	}
+
+	// All messages must have been passed to the listener once Close returned.
+	expected := []Message{
+		{AudioSystem, TV, Standby{}},
+		{TV, AudioSystem, Standby{}},
+	}
+	if diff := cmp.Diff(received, expected, cmp.AllowUnexported(Standby{})); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, received, diff)
+	}
+
+	if err := c.Send(TV, Standby{}); err != (Closed{}) {
+		t.Errorf("Expected %v after close, got %v", Closed{}, err)
+	}
+	if err := c.Close(); err != (Closed{}) {
+		t.Errorf("Expected %v when closing twice, got %v", Closed{}, err)
+	}
+}
+
+func TestClose_NotStarted(t *testing.T) {
+	d := newCloser()
+	c, err := New(d, Config{OSDName: "test"})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	c.SetListenerFunc(func(msg Message) {})
+	if err := c.Close(); err != nil {
+		t.Errorf("Failed to close: %s", err)
+	}
+	if !d.closed {
+		t.Errorf("Expected device to be closed.")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	d := fake.New(AudioSystem, DeviceTypeAudio)
+	c, err := New(d, Config{
+		OSDName: "test",
+		Logger:  slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	d.Run([]Packet{{TV, AudioSystem, 0xfe, nil}}, func() { c.Run() })
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode log record %q: %s", buf.String(), err)
+	}
+	delete(record, "time")
+	expected := map[string]any{
+		"level":     "WARN",
+		"msg":       "Received message with unknown opcode",
+		"initiator": "TV",
+		"follower":  "AudioSystem",
+		"opcode":    "OpCode(254)",
+	}
+	if diff := cmp.Diff(record, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, record, diff)
+	}
+}
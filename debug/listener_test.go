@@ -31,7 +31,7 @@ func TestLoggingHandler(t *testing.T) {
 	l.Message(cec.Message{
 		Initiator: cec.AudioSystem,
 		Follower:  cec.Broadcast,
-		Cmd:       cec.ReportPhysicalAddress{cec.PhysicalAddress(0xabcd), cec.DeviceTypeAudio},
+		Cmd:       cec.ReportPhysicalAddress{Addr: cec.PhysicalAddress(0xabcd), Type: cec.DeviceTypeAudio},
 	})
 
 	if len(l.GetLogged()) != 2 {
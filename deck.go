@@ -0,0 +1,104 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+// A Player plays media, e.g. a video player application. If a method returns an error, the
+// command is refused.
+type Player interface {
+	Play() error
+	Pause() error
+	Stop() error
+
+	// Skips to the next or previous chapter or track, or winds forward or backward.
+	Seek(forward bool) error
+}
+
+// The DeckHandler implements Deck Control for playback devices. It passes Play and DeckControl
+// commands on to a Player and answers GiveDeckStatus. Devices that asked for status reports with
+// StatusRequestOn receive a DeckStatus whenever the deck status changes.
+//
+// Play modes other than PlayForward and PlayStill and ejecting are not supported and aborted.
+type DeckHandler struct {
+	p      Player
+	status statusReporter
+}
+
+// Creates a new DeckHandler with the initial deck status info.
+func NewDeckHandler(p Player, info DeckInfo) *DeckHandler {
+	return &DeckHandler{
+		p:      p,
+		status: newStatusReporter(DeckStatus{Info: info}),
+	}
+}
+
+// Returns the current deck status.
+func (h *DeckHandler) DeckInfo() DeckInfo {
+	return h.status.get().(DeckStatus).Info
+}
+
+// Sets the deck status, e.g. because playback ended or was started by other means than CEC. If
+// the status changed, it's reported to all devices that asked for status reports. Devices that
+// don't acknowledge the report are removed.
+func (h *DeckHandler) SetDeckInfo(x *Cec, info DeckInfo) {
+	h.status.set(x, DeckStatus{Info: info})
+}
+
+// Runs a player command and updates the deck status to info if it succeeds. Aborts the message
+// if the command fails.
+func (h *DeckHandler) run(x *Cec, msg Message, f func() error, info DeckInfo) {
+	if err := f(); err != nil {
+		refuse(x, msg, err)
+		return
+	}
+	h.SetDeckInfo(x, info)
+}
+
+// DeckHandler implements Handler.
+func (h *DeckHandler) HandleMessage(x *Cec, msg Message) bool {
+	if msg.Follower == Broadcast {
+		return false
+	}
+
+	switch cmd := msg.Cmd.(type) {
+	case GiveDeckStatus:
+		h.status.request(x, msg, cmd.Request)
+		return true
+
+	case Play:
+		switch cmd.Mode {
+		case PlayForward:
+			h.run(x, msg, h.p.Play, DeckInfoPlay)
+		case PlayStill:
+			h.run(x, msg, h.p.Pause, DeckInfoStill)
+		default:
+			x.Reply(msg.Initiator, FeatureAbort{Abort: cmd.Op(), Reason: AbortInvalidOperand})
+		}
+		return true
+
+	case DeckControl:
+		switch cmd.Mode {
+		case DeckControlStop:
+			h.run(x, msg, h.p.Stop, DeckInfoStop)
+		case DeckControlSkipForward, DeckControlSkipReverse:
+			// Seeking doesn't change the deck status, the player keeps playing or stays paused.
+			forward := cmd.Mode == DeckControlSkipForward
+			h.run(x, msg, func() error { return h.p.Seek(forward) }, h.DeckInfo())
+		default:
+			x.Reply(msg.Initiator, FeatureAbort{Abort: cmd.Op(), Reason: AbortInvalidOperand})
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+// A Player that records all calls.
+type player struct {
+	calls []string
+	err   error // Returned by all methods if set.
+}
+
+func (p *player) call(name string) error {
+	p.calls = append(p.calls, name)
+	return p.err
+}
+
+func (p *player) Play() error  { return p.call("play") }
+func (p *player) Pause() error { return p.call("pause") }
+func (p *player) Stop() error  { return p.call("stop") }
+func (p *player) Seek(forward bool) error {
+	return p.call(fmt.Sprintf("seek(%t)", forward))
+}
+
+// These tests also cover the status requests and reports shared with the TunerHandler.
+func TestDeckHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		in    []Packet
+		out   []Packet
+		calls []string
+		info  DeckInfo
+	}{
+		{
+			name: "status_once",
+			in: []Packet{
+				{TV, Playback1, OpGiveDeckStatus, []byte{byte(StatusRequestOnce)}},
+				{TV, Playback1, OpPlay, []byte{byte(PlayForward)}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoStop)}},
+			},
+			calls: []string{"play"},
+			info:  DeckInfoPlay,
+		}, {
+			name: "status_on",
+			in: []Packet{
+				{TV, Playback1, OpGiveDeckStatus, []byte{byte(StatusRequestOn)}},
+				{TV, Playback1, OpPlay, []byte{byte(PlayForward)}},
+				{TV, Playback1, OpPlay, []byte{byte(PlayStill)}},
+				{TV, Playback1, OpDeckControl, []byte{byte(DeckControlSkipForward)}},
+				{TV, Playback1, OpDeckControl, []byte{byte(DeckControlSkipReverse)}},
+				{TV, Playback1, OpDeckControl, []byte{byte(DeckControlStop)}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoStop)}},
+				{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoPlay)}},
+				{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoStill)}},
+				{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoStop)}},
+			},
+			calls: []string{"play", "pause", "seek(true)", "seek(false)", "stop"},
+			info:  DeckInfoStop,
+		}, {
+			name: "status_off",
+			in: []Packet{
+				{TV, Playback1, OpGiveDeckStatus, []byte{byte(StatusRequestOn)}},
+				{TV, Playback1, OpGiveDeckStatus, []byte{byte(StatusRequestOff)}},
+				{TV, Playback1, OpPlay, []byte{byte(PlayForward)}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoStop)}},
+			},
+			calls: []string{"play"},
+			info:  DeckInfoPlay,
+		}, {
+			name: "invalid_status_request",
+			in: []Packet{
+				{TV, Playback1, OpGiveDeckStatus, []byte{0x00}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpGiveDeckStatus), byte(AbortInvalidOperand)}},
+			},
+			info: DeckInfoStop,
+		}, {
+			name: "unsupported",
+			in: []Packet{
+				{TV, Playback1, OpPlay, []byte{byte(PlayFastForwardMin)}},
+				{TV, Playback1, OpDeckControl, []byte{byte(DeckControlEject)}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpPlay), byte(AbortInvalidOperand)}},
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpDeckControl), byte(AbortInvalidOperand)}},
+			},
+			info: DeckInfoStop,
+		}, {
+			name: "refused",
+			err:  errors.New("no media"),
+			in: []Packet{
+				{TV, Playback1, OpPlay, []byte{byte(PlayForward)}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpPlay), byte(AbortRefused)}},
+			},
+			calls: []string{"play"},
+			info:  DeckInfoStop,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &player{err: test.err}
+			h := NewDeckHandler(p, DeckInfoStop)
+			d := fake.New(Playback1, DeviceTypePlayback)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(h)
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+			if diff := cmp.Diff(p.calls, test.calls); diff != "" {
+				t.Errorf("Expected calls %v, got %v: %s", test.calls, p.calls, diff)
+			}
+			if info := h.DeckInfo(); info != test.info {
+				t.Errorf("Expected deck info %s, got %s", test.info, info)
+			}
+		})
+	}
+}
+
+func TestDeckHandler_SetDeckInfo(t *testing.T) {
+	h := NewDeckHandler(&player{}, DeckInfoPlay)
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	c.AddHandler(h)
+	in := []Packet{{TV, Playback1, OpGiveDeckStatus, []byte{byte(StatusRequestOn)}}}
+	actual := d.Run(in, func() {
+		c.Run()
+		// Playback ended, the TV is informed once.
+		h.SetDeckInfo(c, DeckInfoStop)
+		h.SetDeckInfo(c, DeckInfoStop)
+		// The TV doesn't acknowledge the report and is removed.
+		d.SetTxStatus(TV, TxNack)
+		h.SetDeckInfo(c, DeckInfoNoMedia)
+		h.SetDeckInfo(c, DeckInfoStop)
+	})
+	expected := []Packet{
+		{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoPlay)}},
+		{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoStop)}},
+		{Playback1, TV, OpDeckStatus, []byte{byte(DeckInfoNoMedia)}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
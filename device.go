@@ -37,7 +37,7 @@ type Device interface {
 	// Sends a CEC packet to follower.
 	Send(follower LogicalAddr, op OpCode, payload []byte)
 
-	// Sens a CEC packet to a follower as a reply.
+	// Sends a CEC packet to a follower as a reply.
 	Reply(follower LogicalAddr, op OpCode, payload []byte)
 
 	// Returns the vendor ID of this device.
@@ -52,3 +52,44 @@ type Device interface {
 	// Returns the logical address of this device.
 	GetLogicalAddress() LogicalAddr
 }
+
+// A StatusDevice is a Device that reports the result of a transmission. If a device implements
+// this interface, Cec.Send and Cec.Reply use SendStatus and ReplyStatus and return their errors.
+type StatusDevice interface {
+	Device
+
+	// Sends a CEC packet to follower and waits until it is transmitted. Returns a TransmitError if
+	// the packet wasn't acknowledged.
+	SendStatus(follower LogicalAddr, op OpCode, payload []byte) error
+
+	// Sends a CEC packet to follower as a reply and waits until it is transmitted. Returns a
+	// TransmitError if the packet wasn't acknowledged.
+	ReplyStatus(follower LogicalAddr, op OpCode, payload []byte) error
+}
+
+// A Poller is a Device that can poll logical addresses to find out whether they are in use.
+type Poller interface {
+	Device
+
+	// Polls the logical address a and returns true if another device acknowledged the poll.
+	PollLogicalAddress(a LogicalAddr) (bool, error)
+}
+
+// An AddressClaimer is a Device that can poll logical addresses and claim one of them. See
+// ClaimLogicalAddress.
+type AddressClaimer interface {
+	Poller
+
+	// Claims the logical address a. Afterwards, GetLogicalAddress returns a and the device
+	// acknowledges packets sent to a. Claiming Unregistered releases the current address.
+	ClaimLogicalAddress(a LogicalAddr) error
+}
+
+// TransmitError is returned if a packet could not be transmitted.
+type TransmitError struct {
+	Status TxStatus
+}
+
+func (e TransmitError) Error() string {
+	return fmt.Sprintf("Transmit failed: %s", e.Status)
+}
@@ -16,6 +16,8 @@
 package fake
 
 import (
+	"sync"
+
 	"znkr.io/cec"
 )
 
@@ -24,6 +26,10 @@ type Device struct {
 	typ  cec.DeviceType
 	ci   chan cec.Packet
 	co   chan cec.Packet
+
+	mtx      sync.Mutex
+	tx       map[cec.LogicalAddr]cec.TxStatus
+	occupied map[cec.LogicalAddr]bool
 }
 
 const PhysicalAddress cec.PhysicalAddress = 0xabcd
@@ -31,10 +37,30 @@ const VendorDeviceID = 0x101010
 
 func New(addr cec.LogicalAddr, typ cec.DeviceType) *Device {
 	return &Device{
-		addr: addr,
-		typ:  typ,
-		ci:   make(chan cec.Packet, 10),
-		co:   make(chan cec.Packet, 10),
+		addr:     addr,
+		typ:      typ,
+		ci:       make(chan cec.Packet, 10),
+		co:       make(chan cec.Packet, 10),
+		tx:       make(map[cec.LogicalAddr]cec.TxStatus),
+		occupied: make(map[cec.LogicalAddr]bool),
+	}
+}
+
+// Sets the transmit status reported for all packets sent to follower. By default, all packets are
+// acknowledged.
+func (d *Device) SetTxStatus(follower cec.LogicalAddr, s cec.TxStatus) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.tx[follower] = s
+}
+
+// Marks logical addresses as used by other devices on the bus. Polls of these addresses are
+// acknowledged.
+func (d *Device) SetOccupied(addrs ...cec.LogicalAddr) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	for _, a := range addrs {
+		d.occupied[a] = true
 	}
 }
 
@@ -65,7 +91,7 @@ func (d *Device) Receive() <-chan cec.Packet {
 
 func (d *Device) Send(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
 	d.co <- cec.Packet{
-		Initiator: d.addr,
+		Initiator: d.GetLogicalAddress(),
 		Follower:  follower,
 		Op:        op,
 		Data:      payload,
@@ -76,6 +102,22 @@ func (d *Device) Reply(follower cec.LogicalAddr, op cec.OpCode, payload []byte)
 	d.Send(follower, op, payload)
 }
 
+// Sends a packet and reports the transmit status set by SetTxStatus. The packet is recorded
+// regardless of the status.
+func (d *Device) SendStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	d.Send(follower, op, payload)
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if s := d.tx[follower]; s != cec.TxAck {
+		return cec.TransmitError{Status: s}
+	}
+	return nil
+}
+
+func (d *Device) ReplyStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	return d.SendStatus(follower, op, payload)
+}
+
 func (d *Device) GetVendorID() uint32 {
 	return VendorDeviceID
 }
@@ -89,5 +131,21 @@ func (d *Device) GetPhysicalAddress() cec.PhysicalAddress {
 }
 
 func (d *Device) GetLogicalAddress() cec.LogicalAddr {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
 	return d.addr
 }
+
+// Reports whether a was marked as occupied by SetOccupied.
+func (d *Device) PollLogicalAddress(a cec.LogicalAddr) (bool, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.occupied[a], nil
+}
+
+func (d *Device) ClaimLogicalAddress(a cec.LogicalAddr) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.addr = a
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	iocWrite = 1
+	iocRead  = 2
+)
+
+func ioc(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | uintptr('a')<<8 | nr
+}
+
+var (
+	adapGCaps     = ioc(iocRead|iocWrite, 0, unsafe.Sizeof(cecCaps{}))
+	adapGPhysAddr = ioc(iocRead, 1, unsafe.Sizeof(uint16(0)))
+	adapSLogAddrs = ioc(iocRead|iocWrite, 4, unsafe.Sizeof(cecLogAddrs{}))
+	transmit      = ioc(iocRead|iocWrite, 5, unsafe.Sizeof(cecMsg{}))
+	receive       = ioc(iocRead|iocWrite, 6, unsafe.Sizeof(cecMsg{}))
+	dqEvent       = ioc(iocRead|iocWrite, 7, unsafe.Sizeof(cecEvent{}))
+	sMode         = ioc(iocWrite, 9, unsafe.Sizeof(uint32(0)))
+)
+
+const (
+	pollIn  = 0x1
+	pollPri = 0x2
+	pollErr = 0x8
+	pollHup = 0x10
+)
+
+// struct pollfd
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+// fileAdapter implements adapter on top of a /dev/cecN character device.
+type fileAdapter struct {
+	fd int
+}
+
+func openAdapter(path string) (adapter, error) {
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAdapter{fd: fd}, nil
+}
+
+func (a *fileAdapter) ioctl(name string, req uintptr, arg unsafe.Pointer) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(a.fd), req, uintptr(arg))
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		case syscall.EAGAIN:
+			return errAgain
+		default:
+			return fmt.Errorf("%s: %w", name, errno)
+		}
+	}
+}
+
+func (a *fileAdapter) capabilities(c *cecCaps) error {
+	return a.ioctl("CEC_ADAP_G_CAPS", adapGCaps, unsafe.Pointer(c))
+}
+
+func (a *fileAdapter) physicalAddress() (uint16, error) {
+	var addr uint16
+	err := a.ioctl("CEC_ADAP_G_PHYS_ADDR", adapGPhysAddr, unsafe.Pointer(&addr))
+	return addr, err
+}
+
+func (a *fileAdapter) setLogicalAddresses(l *cecLogAddrs) error {
+	return a.ioctl("CEC_ADAP_S_LOG_ADDRS", adapSLogAddrs, unsafe.Pointer(l))
+}
+
+func (a *fileAdapter) setMode(mode uint32) error {
+	return a.ioctl("CEC_S_MODE", sMode, unsafe.Pointer(&mode))
+}
+
+func (a *fileAdapter) transmit(m *cecMsg) error {
+	return a.ioctl("CEC_TRANSMIT", transmit, unsafe.Pointer(m))
+}
+
+func (a *fileAdapter) receive(m *cecMsg) error {
+	return a.ioctl("CEC_RECEIVE", receive, unsafe.Pointer(m))
+}
+
+func (a *fileAdapter) dequeueEvent(e *cecEvent) error {
+	return a.ioctl("CEC_DQEVENT", dqEvent, unsafe.Pointer(e))
+}
+
+func (a *fileAdapter) wait(timeout time.Duration) (rx, ev bool, err error) {
+	fds := [1]pollFd{{fd: int32(a.fd), events: pollIn | pollPri}}
+	ts := syscall.NsecToTimespec(int64(timeout))
+	_, _, errno := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&fds[0])), 1,
+		uintptr(unsafe.Pointer(&ts)), 0, 0, 0)
+	if errno == syscall.EINTR {
+		return false, false, nil
+	} else if errno != 0 {
+		return false, false, fmt.Errorf("ppoll: %w", errno)
+	}
+	if fds[0].revents&(pollErr|pollHup) != 0 {
+		return false, false, syscall.ENODEV
+	}
+	return fds[0].revents&pollIn != 0, fds[0].revents&pollPri != 0, nil
+}
+
+func (a *fileAdapter) close() error {
+	return syscall.Close(a.fd)
+}
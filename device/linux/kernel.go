@@ -0,0 +1,187 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"unsafe"
+
+	"znkr.io/cec"
+)
+
+// The types and constants in this file mirror the definitions in <linux/cec.h>. See
+// https://www.kernel.org/doc/html/latest/userspace-api/media/cec/cec-api.html for details.
+
+const (
+	maxMsgSize  = 16
+	maxLogAddrs = 4
+
+	physAddrInvalid = 0xffff
+	vendorIDNone    = 0xffffffff
+)
+
+// Adapter capabilities.
+const (
+	capPhysAddr   = 1 << 0
+	capLogAddrs   = 1 << 1
+	capTransmit   = 1 << 2
+	capPassthru   = 1 << 3
+	capRC         = 1 << 4
+	capMonitorAll = 1 << 5
+)
+
+// Initiator and follower modes for CEC_S_MODE.
+const (
+	modeInitiator            = 0x1 << 0
+	modeExclFollowerPassthru = 0x3 << 4
+)
+
+// Flags for cecLogAddrs.flags.
+const (
+	logAddrsFlAllowUnregFallback = 1 << 0
+)
+
+// Logical address types for cecLogAddrs.logAddrType.
+const (
+	logAddrTypeTV           = 0
+	logAddrTypeRecord       = 1
+	logAddrTypeTuner        = 2
+	logAddrTypePlayback     = 3
+	logAddrTypeAudioSystem  = 4
+	logAddrTypeSpecific     = 5
+	logAddrTypeUnregistered = 6
+)
+
+// Device types for cecLogAddrs.allDeviceTypes.
+const (
+	allDevTypeTV          = 0x80
+	allDevTypeRecord      = 0x40
+	allDevTypeTuner       = 0x20
+	allDevTypePlayback    = 0x10
+	allDevTypeAudioSystem = 0x08
+	allDevTypeSwitch      = 0x04
+)
+
+// CEC version for cecLogAddrs.cecVersion.
+const cecVersion1_3a = 4
+
+// Transmit status flags for cecMsg.txStatus.
+const (
+	txStatusOK         = 1 << 0
+	txStatusArbLost    = 1 << 1
+	txStatusNack       = 1 << 2
+	txStatusLowDrive   = 1 << 3
+	txStatusError      = 1 << 4
+	txStatusMaxRetries = 1 << 5
+	txStatusAborted    = 1 << 6
+	txStatusTimeout    = 1 << 7
+)
+
+// Event types for cecEvent.event.
+const (
+	eventStateChange = 1
+	eventLostMsgs    = 2
+)
+
+// struct cec_caps
+type cecCaps struct {
+	driver            [32]byte
+	name              [32]byte
+	availableLogAddrs uint32
+	capabilities      uint32
+	version           uint32
+}
+
+// struct cec_log_addrs
+type cecLogAddrs struct {
+	logAddr           [maxLogAddrs]uint8
+	logAddrMask       uint16
+	cecVersion        uint8
+	numLogAddrs       uint8
+	vendorID          uint32
+	flags             uint32
+	osdName           [15]byte
+	primaryDeviceType [maxLogAddrs]uint8
+	logAddrType       [maxLogAddrs]uint8
+	allDeviceTypes    [maxLogAddrs]uint8
+	features          [maxLogAddrs][12]uint8
+}
+
+// struct cec_msg
+type cecMsg struct {
+	txTs          uint64
+	rxTs          uint64
+	len           uint32
+	timeout       uint32
+	sequence      uint32
+	flags         uint32
+	msg           [maxMsgSize]uint8
+	reply         uint8
+	rxStatus      uint8
+	txStatus      uint8
+	txArbLostCnt  uint8
+	txNackCnt     uint8
+	txLowDriveCnt uint8
+	txErrorCnt    uint8
+}
+
+// struct cec_event_state_change
+type cecEventStateChange struct {
+	physAddr     uint16
+	logAddrMask  uint16
+	haveConnInfo uint16
+}
+
+// struct cec_event_lost_msgs
+type cecEventLostMsgs struct {
+	lostMsgs uint32
+}
+
+// struct cec_event
+type cecEvent struct {
+	ts    uint64
+	event uint32
+	flags uint32
+	raw   [16]uint32 // Union of cecEventStateChange and cecEventLostMsgs.
+}
+
+func (e *cecEvent) stateChange() *cecEventStateChange {
+	return (*cecEventStateChange)(unsafe.Pointer(&e.raw))
+}
+
+func (e *cecEvent) lostMsgs() *cecEventLostMsgs {
+	return (*cecEventLostMsgs)(unsafe.Pointer(&e.raw))
+}
+
+// Returns the logical address type and the all device types bit for a device type.
+func logAddrTypes(t cec.DeviceType) (uint8, uint8) {
+	switch t {
+	case cec.DeviceTypeTV:
+		return logAddrTypeTV, allDevTypeTV
+	case cec.DeviceTypeRec:
+		return logAddrTypeRecord, allDevTypeRecord
+	case cec.DeviceTypeTuner:
+		return logAddrTypeTuner, allDevTypeTuner
+	case cec.DeviceTypePlayback:
+		return logAddrTypePlayback, allDevTypePlayback
+	case cec.DeviceTypeAudio:
+		return logAddrTypeAudioSystem, allDevTypeAudioSystem
+	case cec.DeviceTypeSwitch:
+		return logAddrTypeUnregistered, allDevTypeSwitch
+	case cec.DeviceTypeVidProc:
+		return logAddrTypeSpecific, allDevTypeSwitch
+	default:
+		return logAddrTypeUnregistered, 0
+	}
+}
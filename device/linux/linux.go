@@ -0,0 +1,409 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package provides a way to use CEC adapters exposed by the Linux kernel CEC framework
+// (/dev/cecN) for CEC.
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"znkr.io/cec"
+	"znkr.io/cec/internal/queue"
+)
+
+const (
+	// How long to wait for the kernel to claim a logical address.
+	configureTimeout = 5 * time.Second
+
+	// How long to wait for new messages or events before checking whether the device was closed.
+	pollInterval = 100 * time.Millisecond
+)
+
+var errAgain = errors.New("resource temporarily unavailable")
+
+// adapter is the seam between the device logic and the kernel CEC API. Each method except wait
+// and close corresponds to one ioctl. The file descriptor is non-blocking, receive and
+// dequeueEvent return errAgain if there is nothing to dequeue.
+type adapter interface {
+	capabilities(c *cecCaps) error
+	physicalAddress() (uint16, error)
+	setLogicalAddresses(l *cecLogAddrs) error
+	setMode(mode uint32) error
+	transmit(m *cecMsg) error
+	receive(m *cecMsg) error
+	dequeueEvent(e *cecEvent) error
+
+	// Waits until a message can be received or an event can be dequeued or until the timeout
+	// expired.
+	wait(timeout time.Duration) (rx, ev bool, err error)
+
+	close() error
+}
+
+// Configuration for a kernel CEC device.
+type Config struct {
+	DeviceType cec.DeviceType // The device type to claim a logical address for.
+	VendorID   uint32         // The vendor ID reported for this device.
+	Logger     *slog.Logger   // Logger for diagnostics, slog.Default() is used if nil.
+}
+
+// Device is a CEC device backed by the Linux kernel CEC framework.
+type Device struct {
+	a         adapter
+	typ       cec.DeviceType
+	vendorID  uint32
+	log       *slog.Logger
+	queue     chan<- cec.Packet
+	in        <-chan cec.Packet
+	done      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+
+	mtx        sync.Mutex
+	physAddr   cec.PhysicalAddress
+	logAddr    cec.LogicalAddr
+	configured bool // Whether the kernel reported any claimed address, including Unregistered.
+
+	// Transmits waiting for their result, keyed by sequence number.
+	txMtx   sync.Mutex
+	pending map[uint32]chan<- uint8
+}
+
+// Opens the CEC adapter at path (e.g. /dev/cec0) and configures it according to c.
+//
+// The kernel claims a free logical address for the requested device type. Open returns once the
+// adapter is configured. All incoming messages are passed through to the device, including the
+// ones the kernel would otherwise process itself.
+func Open(path string, c Config) (*Device, error) {
+	a, err := openAdapter(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDevice(a, c)
+	if err != nil {
+		a.close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func newDevice(a adapter, c Config) (*Device, error) {
+	var caps cecCaps
+	if err := a.capabilities(&caps); err != nil {
+		return nil, err
+	}
+	if caps.capabilities&(capLogAddrs|capTransmit) != capLogAddrs|capTransmit {
+		return nil, fmt.Errorf("adapter %q does not support claiming logical addresses or transmitting",
+			cString(caps.name[:]))
+	}
+
+	if err := a.setMode(modeInitiator | modeExclFollowerPassthru); err != nil {
+		return nil, err
+	}
+
+	physAddr, err := a.physicalAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Device{
+		a:        a,
+		typ:      c.DeviceType,
+		vendorID: c.VendorID,
+		log:      c.Logger,
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+		physAddr: cec.PhysicalAddress(physAddr),
+		logAddr:  cec.Unregistered,
+		pending:  make(map[uint32]chan<- uint8),
+	}
+	if d.log == nil {
+		d.log = slog.Default()
+	}
+	d.queue, d.in = queue.New(d.done)
+	if err := d.configure(); err != nil {
+		return nil, err
+	}
+
+	go d.run()
+	return d, nil
+}
+
+// Claims a logical address and waits until the kernel reports the result.
+func (d *Device) configure() error {
+	// Clear any previous configuration, the kernel refuses to reconfigure a configured adapter.
+	if err := d.a.setLogicalAddresses(&cecLogAddrs{}); err != nil {
+		return err
+	}
+
+	l := cecLogAddrs{
+		cecVersion:  cecVersion1_3a,
+		numLogAddrs: 1,
+		vendorID:    d.vendorID,
+		flags:       logAddrsFlAllowUnregFallback,
+	}
+	l.primaryDeviceType[0] = uint8(d.typ)
+	l.logAddrType[0], l.allDeviceTypes[0] = logAddrTypes(d.typ)
+	if err := d.a.setLogicalAddresses(&l); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(configureTimeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return errors.New("timeout while claiming a logical address")
+		}
+		_, ev, err := d.a.wait(remaining)
+		if err != nil {
+			return err
+		}
+		if ev {
+			d.dequeueEvents()
+			if d.isConfigured() {
+				return nil
+			}
+		}
+	}
+}
+
+func (d *Device) run() {
+	defer close(d.exited)
+	defer close(d.queue)
+	for {
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+
+		rx, ev, err := d.a.wait(pollInterval)
+		if err != nil {
+			d.log.Error("Stopped receiving CEC messages", slog.Any("error", err))
+			return
+		}
+		if ev {
+			d.dequeueEvents()
+		}
+		if rx && !d.receiveAll() {
+			return
+		}
+	}
+}
+
+func (d *Device) dequeueEvents() {
+	for {
+		var e cecEvent
+		if err := d.a.dequeueEvent(&e); err == errAgain {
+			return
+		} else if err != nil {
+			d.log.Warn("Failed to dequeue CEC event", slog.Any("error", err))
+			return
+		}
+
+		switch e.event {
+		case eventStateChange:
+			s := e.stateChange()
+			d.mtx.Lock()
+			d.physAddr = cec.PhysicalAddress(s.physAddr)
+			d.logAddr = firstLogicalAddr(s.logAddrMask)
+			d.configured = s.logAddrMask != 0
+			d.mtx.Unlock()
+
+		case eventLostMsgs:
+			d.log.Warn("Lost CEC messages", slog.Any("count", e.lostMsgs().lostMsgs))
+		}
+	}
+}
+
+// Receives all pending messages. Returns false if the device was closed while doing so.
+func (d *Device) receiveAll() bool {
+	for {
+		var m cecMsg
+		if err := d.a.receive(&m); err == errAgain {
+			return true
+		} else if err != nil {
+			d.log.Warn("Failed to receive CEC message", slog.Any("error", err))
+			return true
+		}
+
+		if m.txStatus != 0 {
+			d.txMtx.Lock()
+			result, ok := d.pending[m.sequence]
+			delete(d.pending, m.sequence)
+			d.txMtx.Unlock()
+			if ok {
+				result <- m.txStatus
+			}
+			continue
+		}
+		if m.len < 2 {
+			continue
+		}
+
+		var payload []byte
+		if m.len > 2 {
+			payload = append([]byte{}, m.msg[2:m.len]...)
+		}
+		p := cec.Packet{
+			Initiator: cec.LogicalAddr((m.msg[0] >> 4) & 0xf),
+			Follower:  cec.LogicalAddr((m.msg[0] >> 0) & 0xf),
+			Op:        cec.OpCode(m.msg[1]),
+			Data:      payload,
+		}
+		select {
+		case d.queue <- p:
+		case <-d.done:
+			return false
+		}
+	}
+}
+
+func (d *Device) transmit(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	if 2+len(payload) > maxMsgSize {
+		return fmt.Errorf("payload too long: %d bytes", len(payload))
+	}
+	var m cecMsg
+	m.msg[0] = byte(d.GetLogicalAddress())<<4 | byte(follower)&0xf
+	m.msg[1] = byte(op)
+	copy(m.msg[2:], payload)
+	m.len = uint32(2 + len(payload))
+
+	// The transmit is non-blocking, the result is reported via CEC_RECEIVE. The sequence number is
+	// registered before the receive loop can look it up.
+	result := make(chan uint8, 1)
+	d.txMtx.Lock()
+	err := d.a.transmit(&m)
+	if err == nil {
+		d.pending[m.sequence] = result
+	}
+	d.txMtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s := <-result:
+		return txError(s)
+	case <-d.done:
+		return errors.New("device closed")
+	}
+}
+
+// Converts a kernel transmit status to an error.
+func txError(status uint8) error {
+	switch {
+	case status&txStatusOK != 0:
+		return nil
+	case status&txStatusNack != 0:
+		return cec.TransmitError{Status: cec.TxNack}
+	case status&txStatusArbLost != 0:
+		return cec.TransmitError{Status: cec.TxArbitrationLost}
+	case status&txStatusLowDrive != 0:
+		return cec.TransmitError{Status: cec.TxLowDrive}
+	case status&txStatusTimeout != 0:
+		return cec.TransmitError{Status: cec.TxTimeout}
+	default:
+		return cec.TransmitError{Status: cec.TxError}
+	}
+}
+
+// Closes the device and the underlying file. This also closes the Receive channel.
+func (d *Device) Close() error {
+	err := errors.New("device already closed")
+	d.closeOnce.Do(func() {
+		close(d.done)
+		<-d.exited
+		err = d.a.close()
+	})
+	return err
+}
+
+func (d *Device) Receive() <-chan cec.Packet {
+	return d.in
+}
+
+func (d *Device) Send(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
+	if err := d.transmit(follower, op, payload); err != nil {
+		d.log.Warn("Failed to send CEC message",
+			slog.String("follower", follower.String()),
+			slog.String("opcode", op.String()),
+			slog.Any("error", err))
+	}
+}
+
+func (d *Device) Reply(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
+	d.Send(follower, op, payload)
+}
+
+func (d *Device) SendStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	return d.transmit(follower, op, payload)
+}
+
+func (d *Device) ReplyStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	return d.transmit(follower, op, payload)
+}
+
+func (d *Device) GetVendorID() uint32 {
+	return d.vendorID
+}
+
+func (d *Device) GetDeviceType() cec.DeviceType {
+	return d.typ
+}
+
+func (d *Device) GetPhysicalAddress() cec.PhysicalAddress {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.physAddr
+}
+
+func (d *Device) GetLogicalAddress() cec.LogicalAddr {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.logAddr
+}
+
+// Returns true if the adapter claimed a logical address. Switches and devices without a free
+// address end up as Unregistered, that counts as configured.
+func (d *Device) isConfigured() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.configured
+}
+
+// Returns the lowest logical address in mask or Unregistered if mask is empty or only contains
+// Unregistered.
+func firstLogicalAddr(mask uint16) cec.LogicalAddr {
+	for a := cec.LogicalAddr(0); a < cec.Unregistered; a++ {
+		if mask&(1<<a) != 0 {
+			return a
+		}
+	}
+	return cec.Unregistered
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,346 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linux
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec"
+)
+
+// fakeAdapter is an in-process stand-in for the kernel CEC framework.
+type fakeAdapter struct {
+	caps     uint32
+	physAddr uint16
+	claim    cec.LogicalAddr // The address to claim when logical addresses are set.
+	txStatus uint8           // The status reported for transmitted messages.
+
+	mtx      sync.Mutex
+	mode     uint32
+	logAddrs cecLogAddrs
+	rx       []cecMsg
+	events   []cecEvent
+	tx       []cecMsg
+	sequence uint32
+	closed   bool
+	notify   chan struct{}
+}
+
+func newFakeAdapter(claim cec.LogicalAddr) *fakeAdapter {
+	return &fakeAdapter{
+		caps:     capPhysAddr | capLogAddrs | capTransmit | capPassthru,
+		physAddr: 0x1000,
+		claim:    claim,
+		txStatus: txStatusOK,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+func (a *fakeAdapter) signal() {
+	select {
+	case a.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (a *fakeAdapter) queueMessage(m cecMsg) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.rx = append(a.rx, m)
+	a.signal()
+}
+
+func (a *fakeAdapter) queueStateChange(physAddr, logAddrMask uint16) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	e := cecEvent{event: eventStateChange}
+	e.stateChange().physAddr = physAddr
+	e.stateChange().logAddrMask = logAddrMask
+	a.events = append(a.events, e)
+	a.signal()
+}
+
+func (a *fakeAdapter) transmitted() []cecMsg {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return append([]cecMsg{}, a.tx...)
+}
+
+func (a *fakeAdapter) capabilities(c *cecCaps) error {
+	copy(c.name[:], "fake")
+	c.capabilities = a.caps
+	return nil
+}
+
+func (a *fakeAdapter) physicalAddress() (uint16, error) {
+	return a.physAddr, nil
+}
+
+func (a *fakeAdapter) setLogicalAddresses(l *cecLogAddrs) error {
+	a.mtx.Lock()
+	a.logAddrs = *l
+	a.mtx.Unlock()
+	if l.numLogAddrs > 0 {
+		a.queueStateChange(a.physAddr, 1<<a.claim)
+	}
+	return nil
+}
+
+func (a *fakeAdapter) setMode(mode uint32) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.mode = mode
+	return nil
+}
+
+func (a *fakeAdapter) transmit(m *cecMsg) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.tx = append(a.tx, *m)
+
+	// Report the result asynchronously, like the kernel does for non-blocking transmits.
+	a.sequence++
+	m.sequence = a.sequence
+	result := *m
+	result.txStatus = a.txStatus
+	a.rx = append(a.rx, result)
+	a.signal()
+	return nil
+}
+
+func (a *fakeAdapter) receive(m *cecMsg) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if len(a.rx) == 0 {
+		return errAgain
+	}
+	*m, a.rx = a.rx[0], a.rx[1:]
+	return nil
+}
+
+func (a *fakeAdapter) dequeueEvent(e *cecEvent) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if len(a.events) == 0 {
+		return errAgain
+	}
+	*e, a.events = a.events[0], a.events[1:]
+	return nil
+}
+
+func (a *fakeAdapter) wait(timeout time.Duration) (rx, ev bool, err error) {
+	check := func() (bool, bool) {
+		a.mtx.Lock()
+		defer a.mtx.Unlock()
+		return len(a.rx) > 0, len(a.events) > 0
+	}
+	if rx, ev := check(); rx || ev {
+		return rx, ev, nil
+	}
+	select {
+	case <-a.notify:
+	case <-time.After(timeout):
+	}
+	rx, ev = check()
+	return rx, ev, nil
+}
+
+func (a *fakeAdapter) close() error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.closed = true
+	return nil
+}
+
+func makeMsg(data ...byte) cecMsg {
+	var m cecMsg
+	m.len = uint32(copy(m.msg[:], data))
+	return m
+}
+
+func TestOpen(t *testing.T) {
+	a := newFakeAdapter(cec.Playback2)
+	d, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback, VendorID: 0x123456})
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	if a.mode != modeInitiator|modeExclFollowerPassthru {
+		t.Errorf("Expected mode %#x, got %#x", modeInitiator|modeExclFollowerPassthru, a.mode)
+	}
+	if a.logAddrs.numLogAddrs != 1 || a.logAddrs.logAddrType[0] != logAddrTypePlayback ||
+		a.logAddrs.primaryDeviceType[0] != uint8(cec.DeviceTypePlayback) || a.logAddrs.vendorID != 0x123456 {
+		t.Errorf("Unexpected logical address configuration %+v", a.logAddrs)
+	}
+	if addr := d.GetLogicalAddress(); addr != cec.Playback2 {
+		t.Errorf("Expected logical address %s, got %s", cec.Playback2, addr)
+	}
+	if addr := d.GetPhysicalAddress(); addr != 0x1000 {
+		t.Errorf("Expected physical address %s, got %s", cec.PhysicalAddress(0x1000), addr)
+	}
+}
+
+func TestOpen_Unregistered(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  cec.DeviceType
+	}{
+		// Switches always use the unregistered address.
+		{"switch", cec.DeviceTypeSwitch},
+		// The kernel falls back to the unregistered address if no address is free.
+		{"fallback", cec.DeviceTypePlayback},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newFakeAdapter(cec.Unregistered)
+			d, err := newDevice(a, Config{DeviceType: test.typ})
+			if err != nil {
+				t.Fatalf("Failed to open device: %s", err)
+			}
+			defer d.Close()
+			if addr := d.GetLogicalAddress(); addr != cec.Unregistered {
+				t.Errorf("Expected logical address %s, got %s", cec.Unregistered, addr)
+			}
+		})
+	}
+}
+
+func TestOpen_Unsupported(t *testing.T) {
+	a := newFakeAdapter(cec.Playback1)
+	a.caps = capPhysAddr
+	if _, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback}); err == nil {
+		t.Errorf("Expected error for adapter without transmit capability, but succeeded.")
+	}
+}
+
+func TestReceive(t *testing.T) {
+	a := newFakeAdapter(cec.Playback1)
+	d, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback})
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	// The result of a transmit must not be reported as a received packet.
+	result := makeMsg(0x40, byte(cec.OpStandby))
+	result.txStatus = txStatusOK
+	a.queueMessage(result)
+	a.queueMessage(makeMsg(0x04, byte(cec.OpGivePhysicalAddress)))
+	a.queueMessage(makeMsg(0x0f, byte(cec.OpActiveSource), 0x10, 0x00))
+
+	expected := []cec.Packet{
+		{Initiator: cec.TV, Follower: cec.Playback1, Op: cec.OpGivePhysicalAddress},
+		{Initiator: cec.TV, Follower: cec.Broadcast, Op: cec.OpActiveSource, Data: []byte{0x10, 0x00}},
+	}
+	actual := []cec.Packet{<-d.Receive(), <-d.Receive()}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestSend(t *testing.T) {
+	a := newFakeAdapter(cec.Playback1)
+	d, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback})
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	d.Send(cec.TV, cec.OpStandby, nil)
+	d.Reply(cec.Broadcast, cec.OpReportPhysicalAddress, []byte{0x10, 0x00, 0x04})
+
+	expected := []cecMsg{
+		makeMsg(0x40, byte(cec.OpStandby)),
+		makeMsg(0x4f, byte(cec.OpReportPhysicalAddress), 0x10, 0x00, 0x04),
+	}
+	actual := a.transmitted()
+	if diff := cmp.Diff(actual, expected, cmp.AllowUnexported(cecMsg{})); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestSendStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		txStatus uint8
+		err      error
+	}{
+		{"ok", txStatusOK, nil},
+		{"nack", txStatusNack | txStatusMaxRetries, cec.TransmitError{Status: cec.TxNack}},
+		{"arbitration_lost", txStatusArbLost | txStatusMaxRetries, cec.TransmitError{Status: cec.TxArbitrationLost}},
+		{"low_drive", txStatusLowDrive | txStatusMaxRetries, cec.TransmitError{Status: cec.TxLowDrive}},
+		{"timeout", txStatusTimeout, cec.TransmitError{Status: cec.TxTimeout}},
+		{"error", txStatusError | txStatusMaxRetries, cec.TransmitError{Status: cec.TxError}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newFakeAdapter(cec.Playback1)
+			a.txStatus = test.txStatus
+			d, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback})
+			if err != nil {
+				t.Fatalf("Failed to open device: %s", err)
+			}
+			defer d.Close()
+
+			if err := d.SendStatus(cec.TV, cec.OpStandby, nil); err != test.err {
+				t.Errorf("Expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestStateChange(t *testing.T) {
+	a := newFakeAdapter(cec.Playback1)
+	d, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback})
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	a.queueStateChange(0x2100, 1<<cec.Playback3)
+	deadline := time.Now().Add(time.Second)
+	for d.GetLogicalAddress() != cec.Playback3 || d.GetPhysicalAddress() != 0x2100 {
+		if time.Now().After(deadline) {
+			t.Fatalf("State change was not applied, got %s and %s", d.GetLogicalAddress(), d.GetPhysicalAddress())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClose(t *testing.T) {
+	a := newFakeAdapter(cec.Playback1)
+	d, err := newDevice(a, Config{DeviceType: cec.DeviceTypePlayback})
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Errorf("Failed to close device: %s", err)
+	}
+	if _, ok := <-d.Receive(); ok {
+		t.Errorf("Expected receive channel to be closed.")
+	}
+	if !a.closed {
+		t.Errorf("Expected adapter to be closed.")
+	}
+	if err := d.Close(); err == nil {
+		t.Errorf("Expected error when closing twice, but succeeded.")
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package pulseeight
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"znkr.io/cec"
+)
+
+// Opens a new pseudo terminal and returns the master and the path to the slave.
+func openPty() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	conn, err := master.SyscallConn()
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+	var n uint32
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		var unlock int32
+		if _, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+			return
+		}
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n)))
+	})
+	if err == nil && errno != 0 {
+		err = errno
+	}
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n), nil
+}
+
+// emulator emulates a Pulse-Eight adapter on the master side of a pseudo terminal.
+type emulator struct {
+	master   *os.File
+	firmware uint16
+	present  map[cec.LogicalAddr]bool // Devices on the bus that acknowledge frames.
+	done     chan struct{}
+
+	mtx        sync.Mutex
+	controlled bool
+	ackMask    uint16
+	frames     [][]byte // Transmitted frames, including polls.
+}
+
+// Starts an emulator and returns it together with the path to the serial port.
+func newEmulator(t *testing.T, present ...cec.LogicalAddr) (*emulator, string) {
+	master, path, err := openPty()
+	if err != nil {
+		t.Skipf("Pseudo terminals not available: %s", err)
+	}
+	e := &emulator{
+		master:   master,
+		firmware: 8,
+		present:  make(map[cec.LogicalAddr]bool),
+		done:     make(chan struct{}),
+	}
+	for _, a := range present {
+		e.present[a] = true
+	}
+	go e.run()
+	t.Cleanup(func() {
+		master.Close()
+		<-e.done
+	})
+	return e, path
+}
+
+func (e *emulator) write(msgs ...message) {
+	var b []byte
+	for _, m := range msgs {
+		b = append(b, m.encode()...)
+	}
+	e.master.Write(b)
+}
+
+func (e *emulator) run() {
+	defer close(e.done)
+
+	var dec decoder
+	var frame []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := e.master.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, c := range buf[:n] {
+			m, ok := dec.feed(c)
+			if !ok {
+				continue
+			}
+			accepted := message{codeCommandAccepted, []byte{byte(m.code)}}
+			switch m.op() {
+			case codePing, codeTransmitAckPolarity:
+				e.write(accepted)
+			case codeFirmwareVersion:
+				e.write(message{codeFirmwareVersion, []byte{byte(e.firmware >> 8), byte(e.firmware)}})
+			case codeSetControlled:
+				e.mtx.Lock()
+				e.controlled = m.params[0] == 1
+				e.mtx.Unlock()
+				e.write(accepted)
+			case codeSetAckMask:
+				e.mtx.Lock()
+				e.ackMask = uint16(m.params[0])<<8 | uint16(m.params[1])
+				e.mtx.Unlock()
+				e.write(accepted)
+			case codeTransmit:
+				frame = append(frame, m.params...)
+				e.write(accepted)
+			case codeTransmitEOM:
+				frame = append(frame, m.params...)
+				e.write(accepted, message{e.transmit(frame), nil})
+				frame = nil
+			default:
+				e.write(message{codeCommandRejected, []byte{byte(m.code)}})
+			}
+		}
+	}
+}
+
+func (e *emulator) transmit(frame []byte) msgCode {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.frames = append(e.frames, frame)
+	follower := cec.LogicalAddr(frame[0] & 0xf)
+	if follower != cec.Broadcast && !e.present[follower] {
+		return codeTransmitFailedAck
+	}
+	return codeTransmitSucceeded
+}
+
+// Simulates a frame received from the bus.
+func (e *emulator) receive(frame []byte) {
+	var msgs []message
+	for i, c := range frame {
+		code := codeFrameData
+		if i == 0 {
+			code = codeFrameStart
+		}
+		if i == len(frame)-1 {
+			code |= flagEOM
+		}
+		msgs = append(msgs, message{code | flagACK, []byte{c}})
+	}
+	e.write(msgs...)
+}
+
+func (e *emulator) transmitted() [][]byte {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return append([][]byte{}, e.frames...)
+}
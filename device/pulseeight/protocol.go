@@ -0,0 +1,125 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulseeight
+
+// Framing bytes of the serial protocol. Every message starts with msgStart and ends with msgEnd.
+// Bytes inside a message that collide with one of these are sent as msgEsc followed by the byte
+// minus escOffset.
+const (
+	msgStart  = 0xff
+	msgEnd    = 0xfe
+	msgEsc    = 0xfd
+	escOffset = 3
+)
+
+// The first byte of every message is the message code. Received frames additionally carry the
+// flagEOM and flagACK bits in the message code.
+type msgCode byte
+
+const (
+	codeNothing                   msgCode = 0
+	codePing                      msgCode = 1
+	codeTimeoutError              msgCode = 2
+	codeHighError                 msgCode = 3
+	codeLowError                  msgCode = 4
+	codeFrameStart                msgCode = 5
+	codeFrameData                 msgCode = 6
+	codeReceiveFailed             msgCode = 7
+	codeCommandAccepted           msgCode = 8
+	codeCommandRejected           msgCode = 9
+	codeSetAckMask                msgCode = 10
+	codeTransmit                  msgCode = 11
+	codeTransmitEOM               msgCode = 12
+	codeTransmitIdleTime          msgCode = 13
+	codeTransmitAckPolarity       msgCode = 14
+	codeTransmitLineTimeout       msgCode = 15
+	codeTransmitSucceeded         msgCode = 16
+	codeTransmitFailedLine        msgCode = 17
+	codeTransmitFailedAck         msgCode = 18
+	codeTransmitFailedTimeoutData msgCode = 19
+	codeTransmitFailedTimeoutLine msgCode = 20
+	codeFirmwareVersion           msgCode = 21
+	codeStartBootloader           msgCode = 22
+	codeGetBuildDate              msgCode = 23
+	codeSetControlled             msgCode = 24
+
+	codeMask msgCode = 0x3f
+	flagACK  msgCode = 0x40
+	flagEOM  msgCode = 0x80
+)
+
+// A single message exchanged with the adapter.
+type message struct {
+	code   msgCode
+	params []byte
+}
+
+// Returns the message code without the frame flags.
+func (m message) op() msgCode { return m.code & codeMask }
+
+// Returns whether this message ends a CEC frame.
+func (m message) eom() bool { return m.code&flagEOM != 0 }
+
+// Encodes a message including framing and escaping.
+func (m message) encode() []byte {
+	b := make([]byte, 0, 2*len(m.params)+4)
+	b = append(b, msgStart)
+	for _, c := range append([]byte{byte(m.code)}, m.params...) {
+		if c >= msgEsc {
+			b = append(b, msgEsc, c-escOffset)
+		} else {
+			b = append(b, c)
+		}
+	}
+	return append(b, msgEnd)
+}
+
+// A decoder assembles messages from a stream of bytes.
+type decoder struct {
+	buf     []byte
+	inMsg   bool
+	escaped bool
+}
+
+// Feeds a single byte into the decoder. Returns a message and true once a message is complete.
+func (d *decoder) feed(c byte) (message, bool) {
+	switch {
+	case c == msgStart:
+		// A start byte always starts a new message, even if the previous one is incomplete.
+		d.buf = d.buf[:0]
+		d.inMsg = true
+		d.escaped = false
+	case !d.inMsg:
+		// Ignore garbage between messages.
+	case c == msgEnd:
+		d.inMsg = false
+		if len(d.buf) == 0 {
+			return message{}, false
+		}
+		m := message{
+			code:   msgCode(d.buf[0]),
+			params: append([]byte{}, d.buf[1:]...),
+		}
+		return m, true
+	case c == msgEsc:
+		d.escaped = true
+	case d.escaped:
+		d.buf = append(d.buf, c+escOffset)
+		d.escaped = false
+	default:
+		d.buf = append(d.buf, c)
+	}
+	return message{}, false
+}
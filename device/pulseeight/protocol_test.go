@@ -0,0 +1,75 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pulseeight
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var messageTests = []struct {
+	name    string
+	msg     message
+	encoded []byte
+}{
+	{"ping", message{codePing, []byte{}}, []byte{0xff, 0x01, 0xfe}},
+	{"set_ack_mask", message{codeSetAckMask, []byte{0x00, 0x10}}, []byte{0xff, 0x0a, 0x00, 0x10, 0xfe}},
+	{"escape_start", message{codeTransmit, []byte{0xff}}, []byte{0xff, 0x0b, 0xfd, 0xfc, 0xfe}},
+	{"escape_end", message{codeTransmit, []byte{0xfe}}, []byte{0xff, 0x0b, 0xfd, 0xfb, 0xfe}},
+	{"escape_esc", message{codeTransmit, []byte{0xfd}}, []byte{0xff, 0x0b, 0xfd, 0xfa, 0xfe}},
+	{"frame_flags", message{codeFrameData | flagEOM | flagACK, []byte{0x36}}, []byte{0xff, 0xc6, 0x36, 0xfe}},
+}
+
+func TestMessage_Encode(t *testing.T) {
+	for _, test := range messageTests {
+		t.Run(test.name, func(t *testing.T) {
+			if b := test.msg.encode(); !bytes.Equal(b, test.encoded) {
+				t.Errorf("Message %v encoded to %#v, expected %#v", test.msg, b, test.encoded)
+			}
+		})
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	for _, test := range messageTests {
+		t.Run(test.name, func(t *testing.T) {
+			// Garbage and an incomplete message in front of the message must be ignored.
+			in := append([]byte{0x42, 0xff, 0x01}, test.encoded...)
+			var d decoder
+			var msgs []message
+			for _, c := range in {
+				if m, ok := d.feed(c); ok {
+					msgs = append(msgs, m)
+				}
+			}
+			expected := []message{test.msg}
+			if diff := cmp.Diff(msgs, expected, cmp.AllowUnexported(message{})); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", expected, msgs, diff)
+			}
+		})
+	}
+}
+
+func TestMessage_Flags(t *testing.T) {
+	m := message{code: codeFrameData | flagEOM | flagACK}
+	if m.op() != codeFrameData {
+		t.Errorf("Expected op %d, got %d", codeFrameData, m.op())
+	}
+	if !m.eom() {
+		t.Errorf("Expected message to end the frame.")
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package provides a way to use the Pulse-Eight USB-CEC adapter for CEC.
+package pulseeight
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"znkr.io/cec"
+	"znkr.io/cec/internal/queue"
+)
+
+const (
+	vendorID = 0x001582 // Pulse-Eight
+
+	// How long to wait for the adapter to respond to a command.
+	responseTimeout = time.Second
+)
+
+var errTimeout = errors.New("timeout waiting for adapter response")
+
+// Configuration for a Pulse-Eight device.
+type Config struct {
+	LogicalAddr     cec.LogicalAddr     // The logical address to claim.
+	DeviceType      cec.DeviceType      // The device type of this device.
+	PhysicalAddress cec.PhysicalAddress // The physical address of the HDMI port the adapter is connected to.
+	Logger          *slog.Logger        // Logger for diagnostics, slog.Default() is used if nil.
+}
+
+// Device is a CEC device backed by a Pulse-Eight USB-CEC adapter.
+type Device struct {
+	port      io.ReadWriteCloser
+	addrMtx   sync.Mutex
+	addr      cec.LogicalAddr
+	typ       cec.DeviceType
+	physAddr  cec.PhysicalAddress
+	firmware  uint16
+	log       *slog.Logger
+	queue     chan<- cec.Packet
+	in        <-chan cec.Packet
+	resp      chan message
+	done      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+
+	// Serializes commands, the adapter only handles one command at a time.
+	mtx sync.Mutex
+}
+
+// Opens the Pulse-Eight adapter connected to the serial port at path (e.g. /dev/ttyACM0) and
+// configures it according to c.
+func Open(path string, c Config) (*Device, error) {
+	port, err := openSerial(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(port, c)
+}
+
+// Creates a new device that communicates with a Pulse-Eight adapter over port. The port is closed
+// when the device is closed or when the initialization fails.
+//
+// The adapter is switched into controlled mode and acknowledges frames for the configured logical
+// address. New fails if the logical address is already used by another device. If the logical
+// address is Unregistered, cec.ClaimLogicalAddress can be used to claim a free address later.
+func New(port io.ReadWriteCloser, c Config) (*Device, error) {
+	d := &Device{
+		port:     port,
+		addr:     c.LogicalAddr,
+		typ:      c.DeviceType,
+		physAddr: c.PhysicalAddress,
+		log:      c.Logger,
+		resp:     make(chan message, 16),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	if d.log == nil {
+		d.log = slog.Default()
+	}
+	d.queue, d.in = queue.New(d.done)
+	go d.read()
+	if err := d.init(); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Device) init() error {
+	if _, err := d.command(codePing); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	m, err := d.command(codeFirmwareVersion)
+	if err != nil {
+		return fmt.Errorf("firmware version: %w", err)
+	}
+	if len(m.params) != 2 {
+		return fmt.Errorf("firmware version: unexpected response %v", m.params)
+	}
+	d.firmware = uint16(m.params[0])<<8 | uint16(m.params[1])
+
+	// Older firmware versions don't have an autonomous mode and are always controlled.
+	if d.firmware >= 2 {
+		if _, err := d.command(codeSetControlled, 1); err != nil {
+			return fmt.Errorf("set controlled: %w", err)
+		}
+	}
+
+	if d.addr != cec.Unregistered {
+		present, err := d.poll(d.addr)
+		if err != nil {
+			return fmt.Errorf("poll %s: %w", d.addr, err)
+		}
+		if present {
+			return fmt.Errorf("logical address %s already in use", d.addr)
+		}
+	}
+	return d.ClaimLogicalAddress(d.addr)
+}
+
+func (d *Device) read() {
+	defer close(d.exited)
+	defer close(d.queue)
+
+	var dec decoder
+	var frame []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := d.port.Read(buf)
+		if err != nil {
+			select {
+			case <-d.done:
+			default:
+				d.log.Error("Stopped reading from Pulse-Eight adapter", slog.Any("error", err))
+			}
+			return
+		}
+		for _, c := range buf[:n] {
+			m, ok := dec.feed(c)
+			if !ok {
+				continue
+			}
+			switch m.op() {
+			case codeFrameStart:
+				frame = append(frame[:0], m.params...)
+			case codeFrameData:
+				frame = append(frame, m.params...)
+			case codeHighError, codeLowError, codeReceiveFailed, codeTimeoutError:
+				// The frame in progress is broken.
+				frame = frame[:0]
+				continue
+			default:
+				select {
+				case d.resp <- m:
+				default:
+					d.log.Warn("Dropped unexpected Pulse-Eight message", slog.Int("code", int(m.code)))
+				}
+				continue
+			}
+			if m.eom() {
+				if !d.deliver(frame) {
+					return
+				}
+				frame = frame[:0]
+			}
+		}
+	}
+}
+
+// Delivers a received frame. Returns false if the device was closed while doing so.
+func (d *Device) deliver(frame []byte) bool {
+	// Polls don't carry an opcode and are handled by the adapter.
+	if len(frame) < 2 {
+		return true
+	}
+	p := cec.Packet{
+		Initiator: cec.LogicalAddr((frame[0] >> 4) & 0xf),
+		Follower:  cec.LogicalAddr((frame[0] >> 0) & 0xf),
+		Op:        cec.OpCode(frame[1]),
+	}
+	// The adapter reports all frames on the bus, only pass on those that are meant for us.
+	if p.Follower != d.GetLogicalAddress() && p.Follower != cec.Broadcast {
+		return true
+	}
+	if len(frame) > 2 {
+		p.Data = append([]byte{}, frame[2:]...)
+	}
+	select {
+	case d.queue <- p:
+		return true
+	case <-d.done:
+		return false
+	}
+}
+
+// Waits for a response from the adapter. The done function is called for each response and
+// returns true if the response concludes the command.
+func (d *Device) await(done func(m message) bool) (message, error) {
+	timeout := time.NewTimer(responseTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case m := <-d.resp:
+			if m.op() == codeCommandRejected {
+				return m, errors.New("command rejected")
+			}
+			if done(m) {
+				return m, nil
+			}
+		case <-timeout.C:
+			return message{}, errTimeout
+		case <-d.exited:
+			return message{}, errors.New("device closed")
+		}
+	}
+}
+
+// Drops stale responses, e.g. from a previous command that timed out.
+func (d *Device) drain() {
+	for {
+		select {
+		case <-d.resp:
+		default:
+			return
+		}
+	}
+}
+
+// Sends a command to the adapter and waits until it was accepted or answered.
+func (d *Device) command(code msgCode, params ...byte) (message, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.drain()
+	if _, err := d.port.Write(message{code, params}.encode()); err != nil {
+		return message{}, err
+	}
+	return d.await(func(m message) bool {
+		switch code {
+		case codeFirmwareVersion:
+			return m.op() == codeFirmwareVersion
+		default:
+			return m.op() == codeCommandAccepted
+		}
+	})
+}
+
+// Transmits a CEC frame and waits for the result.
+func (d *Device) transmit(frame []byte) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var b []byte
+	var polarity byte
+	if cec.LogicalAddr(frame[0]&0xf) == cec.Broadcast {
+		polarity = 1
+	}
+	b = append(b, message{codeTransmitAckPolarity, []byte{polarity}}.encode()...)
+	for i, c := range frame {
+		code := codeTransmit
+		if i == len(frame)-1 {
+			code = codeTransmitEOM
+		}
+		b = append(b, message{code, []byte{c}}.encode()...)
+	}
+
+	d.drain()
+	if _, err := d.port.Write(b); err != nil {
+		return err
+	}
+	m, err := d.await(func(m message) bool {
+		switch m.op() {
+		case codeTransmitSucceeded, codeTransmitFailedAck, codeTransmitFailedLine,
+			codeTransmitFailedTimeoutData, codeTransmitFailedTimeoutLine:
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	switch m.op() {
+	case codeTransmitFailedAck:
+		return cec.TransmitError{Status: cec.TxNack}
+	case codeTransmitFailedLine:
+		return cec.TransmitError{Status: cec.TxArbitrationLost}
+	case codeTransmitFailedTimeoutData, codeTransmitFailedTimeoutLine:
+		return cec.TransmitError{Status: cec.TxTimeout}
+	}
+	return nil
+}
+
+// Polls a logical address and returns true if a device acknowledged the poll.
+func (d *Device) poll(a cec.LogicalAddr) (bool, error) {
+	err := d.transmit([]byte{byte(a)<<4 | byte(a)})
+	if err == (cec.TransmitError{Status: cec.TxNack}) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Polls a logical address and returns true if a device acknowledged the poll.
+func (d *Device) PollLogicalAddress(a cec.LogicalAddr) (bool, error) {
+	return d.poll(a)
+}
+
+// Configures the adapter to acknowledge frames sent to a and to use a as initiator.
+func (d *Device) ClaimLogicalAddress(a cec.LogicalAddr) error {
+	var mask uint16
+	if a != cec.Unregistered {
+		mask = 1 << a
+	}
+	if _, err := d.command(codeSetAckMask, byte(mask>>8), byte(mask)); err != nil {
+		return fmt.Errorf("set ack mask: %w", err)
+	}
+	d.addrMtx.Lock()
+	defer d.addrMtx.Unlock()
+	d.addr = a
+	return nil
+}
+
+// Returns the firmware version of the adapter.
+func (d *Device) FirmwareVersion() uint16 {
+	return d.firmware
+}
+
+// Closes the device and the underlying port. This also closes the Receive channel.
+func (d *Device) Close() error {
+	err := errors.New("device already closed")
+	d.closeOnce.Do(func() {
+		close(d.done)
+		err = d.port.Close()
+		<-d.exited
+	})
+	return err
+}
+
+func (d *Device) Receive() <-chan cec.Packet {
+	return d.in
+}
+
+func (d *Device) Send(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
+	if err := d.SendStatus(follower, op, payload); err != nil {
+		d.log.Warn("Failed to send CEC message",
+			slog.String("follower", follower.String()),
+			slog.String("opcode", op.String()),
+			slog.Any("error", err))
+	}
+}
+
+func (d *Device) Reply(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
+	d.Send(follower, op, payload)
+}
+
+func (d *Device) SendStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	initiator := d.GetLogicalAddress()
+	return d.transmit(append([]byte{byte(initiator)<<4 | byte(follower)&0xf, byte(op)}, payload...))
+}
+
+func (d *Device) ReplyStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	return d.SendStatus(follower, op, payload)
+}
+
+func (d *Device) GetVendorID() uint32 {
+	return vendorID
+}
+
+func (d *Device) GetDeviceType() cec.DeviceType {
+	return d.typ
+}
+
+func (d *Device) GetPhysicalAddress() cec.PhysicalAddress {
+	return d.physAddr
+}
+
+func (d *Device) GetLogicalAddress() cec.LogicalAddr {
+	d.addrMtx.Lock()
+	defer d.addrMtx.Unlock()
+	return d.addr
+}
@@ -0,0 +1,160 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package pulseeight
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec"
+)
+
+var config = Config{
+	LogicalAddr:     cec.Playback1,
+	DeviceType:      cec.DeviceTypePlayback,
+	PhysicalAddress: 0x1000,
+}
+
+func TestOpen(t *testing.T) {
+	e, path := newEmulator(t, cec.TV)
+	d, err := Open(path, config)
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	if v := d.FirmwareVersion(); v != e.firmware {
+		t.Errorf("Expected firmware version %d, got %d", e.firmware, v)
+	}
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if !e.controlled {
+		t.Errorf("Expected adapter to be in controlled mode.")
+	}
+	if e.ackMask != 1<<cec.Playback1 {
+		t.Errorf("Expected ack mask %#x, got %#x", 1<<cec.Playback1, e.ackMask)
+	}
+	// The logical address must be polled before it's claimed.
+	expected := [][]byte{{0x44}}
+	if diff := cmp.Diff(e.frames, expected); diff != "" {
+		t.Errorf("Expected frames %#v, got %#v: %s", expected, e.frames, diff)
+	}
+}
+
+func TestOpen_AddressInUse(t *testing.T) {
+	_, path := newEmulator(t, cec.TV, cec.Playback1)
+	if _, err := Open(path, config); err == nil {
+		t.Errorf("Expected error for logical address in use, but succeeded.")
+	}
+}
+
+func TestClaimLogicalAddress(t *testing.T) {
+	e, path := newEmulator(t, cec.TV, cec.Playback1)
+	d, err := Open(path, Config{
+		LogicalAddr: cec.Unregistered,
+		DeviceType:  cec.DeviceTypePlayback,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	addr, err := cec.ClaimLogicalAddress(d, cec.DeviceTypePlayback)
+	if err != nil {
+		t.Fatalf("Failed to claim logical address: %s", err)
+	}
+	if addr != cec.Playback2 {
+		t.Errorf("Expected logical address %s, got %s", cec.Playback2, addr)
+	}
+	if addr := d.GetLogicalAddress(); addr != cec.Playback2 {
+		t.Errorf("Expected device to have logical address %s, got %s", cec.Playback2, addr)
+	}
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.ackMask != 1<<cec.Playback2 {
+		t.Errorf("Expected ack mask %#x, got %#x", 1<<cec.Playback2, e.ackMask)
+	}
+	expected := [][]byte{{0x44}, {0x88}}
+	if diff := cmp.Diff(e.frames, expected); diff != "" {
+		t.Errorf("Expected frames %#v, got %#v: %s", expected, e.frames, diff)
+	}
+}
+
+func TestSend(t *testing.T) {
+	e, path := newEmulator(t, cec.TV)
+	d, err := Open(path, config)
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	d.Send(cec.TV, cec.OpStandby, nil)
+	d.Reply(cec.Broadcast, cec.OpActiveSource, []byte{0x10, 0x00})
+	nack := cec.TransmitError{Status: cec.TxNack}
+	if err := d.SendStatus(cec.Playback2, cec.OpStandby, nil); err != nack {
+		t.Errorf("Expected %v for frame to absent device, got %v", nack, err)
+	}
+
+	expected := [][]byte{
+		{0x44},
+		{0x40, byte(cec.OpStandby)},
+		{0x4f, byte(cec.OpActiveSource), 0x10, 0x00},
+		{0x48, byte(cec.OpStandby)},
+	}
+	actual := e.transmitted()
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected frames %#v, got %#v: %s", expected, actual, diff)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	e, path := newEmulator(t, cec.TV)
+	d, err := Open(path, config)
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	defer d.Close()
+
+	e.receive([]byte{0x05})                                       // Poll
+	e.receive([]byte{0x05, byte(cec.OpGiveAudioStatus)})          // Addressed to someone else
+	e.receive([]byte{0x04, byte(cec.OpGivePhysicalAddress)})      // Addressed to us
+	e.receive([]byte{0x0f, byte(cec.OpActiveSource), 0xfe, 0xff}) // Broadcast, needs escaping
+
+	expected := []cec.Packet{
+		{Initiator: cec.TV, Follower: cec.Playback1, Op: cec.OpGivePhysicalAddress},
+		{Initiator: cec.TV, Follower: cec.Broadcast, Op: cec.OpActiveSource, Data: []byte{0xfe, 0xff}},
+	}
+	actual := []cec.Packet{<-d.Receive(), <-d.Receive()}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestClose(t *testing.T) {
+	_, path := newEmulator(t, cec.TV)
+	d, err := Open(path, config)
+	if err != nil {
+		t.Fatalf("Failed to open device: %s", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Failed to close device: %s", err)
+	}
+	if _, ok := <-d.Receive(); ok {
+		t.Errorf("Expected receive channel to be closed.")
+	}
+}
@@ -19,7 +19,11 @@
 package raspberrypi
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/cgo"
+	"sync"
 	"unsafe"
 
 	"znkr.io/cec"
@@ -33,15 +37,46 @@ import (
 
 extern void rpi_cec_callback(void*, uint32_t, uint32_t, uint32_t, uint32_t, uint32_t);
 
-// Gateway function to register the callback.
-static inline void register_rpi_cec_callback() {
-  vc_cec_register_callback(rpi_cec_callback, NULL);
+// Gateway function to register the callback. The handle is passed back to the callback.
+static inline void register_rpi_cec_callback(uintptr_t handle) {
+  vc_cec_register_callback(rpi_cec_callback, (void*)handle);
+}
+
+// Gateway function to unregister the callback.
+static inline void unregister_rpi_cec_callback() {
+  vc_cec_register_callback(NULL, NULL);
 }
 */
 import "C"
 
 const vendorID = 0x18C086 // Broadcom
 
+// AddressInUse is returned by Init if the logical address is claimed by another device.
+type AddressInUse struct {
+	Addr cec.LogicalAddr
+}
+
+func (e AddressInUse) Error() string {
+	return fmt.Sprintf("Logical address %s already in use.", e.Addr)
+}
+
+// FirmwareError is returned if a call into the VideoCore firmware fails.
+type FirmwareError struct {
+	Call string // The name of the firmware call.
+	Code int    // The error code returned by the firmware.
+}
+
+func (e FirmwareError) Error() string {
+	return fmt.Sprintf("%s failed with error code %d.", e.Call, e.Code)
+}
+
+// DeviceInUse is returned by Init if the device is already initialized and wasn't closed.
+type DeviceInUse struct{}
+
+func (e DeviceInUse) Error() string {
+	return fmt.Sprintf("Device already in use.")
+}
+
 type incoming struct {
 	n   notify
 	rc  uint32
@@ -54,17 +89,39 @@ type outgoing struct {
 	reply bool
 }
 
+// Configuration for the Raspberry Pi device.
+type Config struct {
+	LogicalAddr cec.LogicalAddr // The logical address to claim, Unregistered to pick a free one.
+	DeviceType  cec.DeviceType  // The device type of this device.
+	Logger      *slog.Logger    // Logger for diagnostics, slog.Default() is used if nil.
+}
+
 type device struct {
 	out        chan outgoing
 	in         chan cec.Packet
 	deviceType cec.DeviceType
+	log        *slog.Logger
+	handle     cgo.Handle // Passed to the firmware callback to find this device.
+	done       chan struct{}
+	exited     chan struct{}
+	closeOnce  sync.Once
 }
 
-var packets chan<- cec.Packet
+// The firmware only supports one callback, inUse is true while a device is initialized.
+var (
+	mtx   sync.Mutex
+	inUse bool
+)
 
-func handleOutgoing(out <-chan outgoing) {
+func (d *device) handleOutgoing() {
+	defer close(d.exited)
 	for {
-		p := <-out
+		var p outgoing
+		select {
+		case p = <-d.out:
+		case <-d.done:
+			return
+		}
 
 		var replyC C.vcos_bool_t
 		if p.reply {
@@ -73,11 +130,16 @@ func handleOutgoing(out <-chan outgoing) {
 			replyC = C.VC_FALSE
 		}
 		ptr := unsafe.Pointer(&p.data[0])
-		C.vc_cec_send_message(
+		if rc := C.vc_cec_send_message(
 			C.uint32_t(p.addr),
 			(*C.uint8_t)(ptr),
 			C.uint32_t(len(p.data)),
-			replyC)
+			replyC); rc != 0 {
+			d.log.Warn("Failed to send CEC message",
+				slog.String("follower", p.addr.String()),
+				slog.String("opcode", cec.OpCode(p.data[0]).String()),
+				slog.Any("error", FirmwareError{"vc_cec_send_message", int(rc)}))
+		}
 	}
 }
 
@@ -86,13 +148,18 @@ func handleOutgoing(out <-chan outgoing) {
 //
 //export rpi_cec_callback
 func rpi_cec_callback(p unsafe.Pointer, hdr, p1, p2, p3, p4 uint32) {
+	d, ok := cgo.Handle(uintptr(p)).Value().(*device)
+	if !ok {
+		return
+	}
 	n := notify((hdr >> 0) & 0xffff)
 
 	switch n {
 	case notifyRx, notifyButtonPressed, notifyButtonRelease:
 		l := (hdr >> 16) & 0xff
 		if l < 1 {
-			log.Panic("Message too small")
+			d.log.Warn("Dropped CEC message that is too small", slog.Int("length", int(l)))
+			return
 		}
 		var data [32]byte
 		for i, p := range []uint32{p1, p2, p3, p4} {
@@ -110,52 +177,118 @@ func rpi_cec_callback(p unsafe.Pointer, hdr, p1, p2, p3, p4 uint32) {
 			payload = data[2:l]
 		}
 
-		packets <- cec.Packet{
+		select {
+		case d.in <- cec.Packet{
 			Initiator: initiator,
 			Follower:  follower,
 			Op:        op,
 			Data:      payload,
+		}:
+		case <-d.done:
 		}
 	}
 }
 
-// Initializes the Raspberry CEC device.
+// Initializes the Raspberry CEC device according to c.
 //
-// This method may only be called once.
-func Init(a cec.LogicalAddr, t cec.DeviceType) cec.Device {
-	if packets != nil {
-		log.Fatal("Device already in use")
+// Only one device can be initialized at a time, Init returns DeviceInUse otherwise. After the
+// device was closed, Init may be called again. If the logical address is claimed by another
+// device, Init returns AddressInUse. If the logical address is Unregistered, a free logical
+// address for the device type is claimed using cec.ClaimLogicalAddress.
+func Init(c Config) (cec.Device, error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if inUse {
+		return nil, DeviceInUse{}
 	}
+
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	a, t := c.LogicalAddr, c.DeviceType
 	d := &device{
 		out:        make(chan outgoing),
 		in:         make(chan cec.Packet),
 		deviceType: t,
+		log:        logger,
+		done:       make(chan struct{}),
+		exited:     make(chan struct{}),
 	}
-	packets = d.in
+	d.handle = cgo.NewHandle(d)
 	C.bcm_host_init()
 	C.vc_cec_set_passive(C.VC_TRUE)
-	C.register_rpi_cec_callback()
+	C.register_rpi_cec_callback(C.uintptr_t(d.handle))
 	C.vc_cec_register_all()
 
-	// If the logical address is not the one we want, try to set it.
-	if d.GetLogicalAddress() != a {
-		d.releaseLogicalAddress()
-
-		if d.pollLogicalAddress(a) {
-			log.Fatalf("Logical address %s already in use", a)
+	if a == cec.Unregistered {
+		// Release the current address first, otherwise polling it would succeed.
+		err := d.releaseLogicalAddress()
+		if err == nil {
+			a, err = cec.ClaimLogicalAddress(d, t)
 		}
-
-		d.setLogicalAddress(a, t)
-		if addr := d.GetLogicalAddress(); addr != a {
-			log.Fatalf("Incorrect logical address: %s", addr)
+		if err != nil {
+			d.unregister()
+			return nil, err
 		}
+	} else if err := d.claim(a); err != nil {
+		d.unregister()
+		return nil, err
 	}
 
-	go handleOutgoing(d.out)
+	inUse = true
+	go d.handleOutgoing()
 
-	log.Printf("Physical address: %s", d.GetPhysicalAddress())
-	log.Printf("Logical address: %s", a)
-	return d
+	d.log.Info("Initialized Raspberry Pi CEC device",
+		slog.String("physical_addr", d.GetPhysicalAddress().String()),
+		slog.String("logical_addr", a.String()))
+	return d, nil
+}
+
+// Claims the logical address a, if the device doesn't already have it.
+func (d *device) claim(a cec.LogicalAddr) error {
+	addr, err := d.logicalAddress()
+	if err != nil {
+		return err
+	}
+	if addr == a {
+		return nil
+	}
+
+	if err := d.releaseLogicalAddress(); err != nil {
+		return err
+	}
+	present, err := d.PollLogicalAddress(a)
+	if err != nil {
+		return err
+	}
+	if present {
+		return AddressInUse{a}
+	}
+	return d.ClaimLogicalAddress(a)
+}
+
+// Stops the callback from referring to this device.
+func (d *device) unregister() {
+	C.unregister_rpi_cec_callback()
+	d.handle.Delete()
+}
+
+// Stops sending messages and drops all messages received afterwards. Afterwards, Init may be
+// called again.
+func (d *device) Close() error {
+	err := errors.New("device already closed")
+	d.closeOnce.Do(func() {
+		close(d.done)
+		<-d.exited
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		d.unregister()
+		inUse = false
+		err = nil
+	})
+	return err
 }
 
 func (d *device) Receive() <-chan cec.Packet {
@@ -163,18 +296,24 @@ func (d *device) Receive() <-chan cec.Packet {
 }
 
 func (d *device) Send(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
-	d.out <- outgoing{
+	select {
+	case d.out <- outgoing{
 		addr:  follower,
 		data:  append([]byte{byte(op)}, payload...),
 		reply: false,
+	}:
+	case <-d.done:
 	}
 }
 
 func (d *device) Reply(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
-	d.out <- outgoing{
+	select {
+	case d.out <- outgoing{
 		addr:  follower,
 		data:  append([]byte{byte(op)}, payload...),
 		reply: true,
+	}:
+	case <-d.done:
 	}
 }
 
@@ -188,44 +327,69 @@ func (d *device) GetDeviceType() cec.DeviceType {
 
 func (d *device) GetPhysicalAddress() cec.PhysicalAddress {
 	var address C.uint16_t
-	if C.vc_cec_get_physical_address(&address) != 0 {
-		log.Fatal("Failed to get physical address.")
+	if rc := C.vc_cec_get_physical_address(&address); rc != 0 {
+		d.log.Error("Failed to get physical address",
+			slog.Any("error", FirmwareError{"vc_cec_get_physical_address", int(rc)}))
+		return cec.InvalidAddress
 	}
 	return cec.PhysicalAddress(address)
 }
 
 func (d *device) GetLogicalAddress() cec.LogicalAddr {
+	addr, err := d.logicalAddress()
+	if err != nil {
+		d.log.Error("Failed to get logical address", slog.Any("error", err))
+		return cec.Unregistered
+	}
+	return addr
+}
+
+func (d *device) logicalAddress() (cec.LogicalAddr, error) {
 	var address C.CEC_AllDevices_T
-	if C.vc_cec_get_logical_address(&address) != 0 {
-		log.Fatal("Failed to get logical address.")
+	if rc := C.vc_cec_get_logical_address(&address); rc != 0 {
+		return cec.Unregistered, FirmwareError{"vc_cec_get_logical_address", int(rc)}
 	}
-	return cec.LogicalAddr(address)
+	return cec.LogicalAddr(address), nil
 }
 
-func (d *device) setLogicalAddress(a cec.LogicalAddr, t cec.DeviceType) {
+// Polls the logical address a and returns true if a device acknowledged the poll.
+func (d *device) PollLogicalAddress(a cec.LogicalAddr) (bool, error) {
 	aC := C.CEC_AllDevices_T(a)
-	tC := C.CEC_DEVICE_TYPE_T(t)
-	vendorIdC := C.uint32_t(vendorID)
-	if C.vc_cec_set_logical_address(aC, tC, vendorIdC) != 0 {
-		log.Fatal("Failed to set logical address.")
+	r := C.vc_cec_poll_address(aC)
+	if r < 0 {
+		return false, FirmwareError{"vc_cec_poll_address", int(r)}
 	}
+	return r == 0, nil
 }
 
-func (d *device) releaseLogicalAddress() {
-	if C.vc_cec_release_logical_address() != 0 {
-		log.Fatal("Failed to release logical address.")
+func (d *device) ClaimLogicalAddress(a cec.LogicalAddr) error {
+	if a == cec.Unregistered {
+		return d.releaseLogicalAddress()
 	}
+	if err := d.setLogicalAddress(a, d.deviceType); err != nil {
+		return err
+	}
+	if addr, err := d.logicalAddress(); err != nil {
+		return err
+	} else if addr != a {
+		return fmt.Errorf("incorrect logical address %s, expected %s", addr, a)
+	}
+	return nil
 }
 
-func (d *device) pollLogicalAddress(a cec.LogicalAddr) bool {
+func (d *device) setLogicalAddress(a cec.LogicalAddr, t cec.DeviceType) error {
 	aC := C.CEC_AllDevices_T(a)
-	r := C.vc_cec_poll_address(aC)
-	if r < 0 {
-		log.Fatal("Failed to poll logical address.")
-	} else if r == 0 {
-		return true
-	} else {
-		return false
+	tC := C.CEC_DEVICE_TYPE_T(t)
+	vendorIdC := C.uint32_t(vendorID)
+	if rc := C.vc_cec_set_logical_address(aC, tC, vendorIdC); rc != 0 {
+		return FirmwareError{"vc_cec_set_logical_address", int(rc)}
+	}
+	return nil
+}
+
+func (d *device) releaseLogicalAddress() error {
+	if rc := C.vc_cec_release_logical_address(); rc != 0 {
+		return FirmwareError{"vc_cec_release_logical_address", int(rc)}
 	}
-	panic("never reached")
+	return nil
 }
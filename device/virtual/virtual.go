@@ -0,0 +1,255 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This package provides an in-process CEC bus with multiple attached devices, e.g. to test the
+// interaction of several cec.Cec instances without hardware.
+package virtual
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"znkr.io/cec"
+	"znkr.io/cec/internal/queue"
+)
+
+// Bus connects all devices attached to it.
+type Bus struct {
+	mtx     sync.Mutex
+	devices []*Device
+	frames  []cec.Packet
+	paused  bool
+	pending []*transmission // Transmissions waiting for arbitration while the bus is paused.
+}
+
+type transmission struct {
+	sender *Device
+	p      cec.Packet
+	result chan error
+}
+
+// Returns the raw frame of the transmission, used to determine the arbitration order.
+func (t *transmission) frame() []byte {
+	return append([]byte{byte(t.p.Initiator)<<4 | byte(t.p.Follower)&0xf, byte(t.p.Op)}, t.p.Data...)
+}
+
+// Creates a new empty bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Configuration for a device attached to a bus.
+type Config struct {
+	LogicalAddr     cec.LogicalAddr     // The logical address of the device.
+	PhysicalAddress cec.PhysicalAddress // The physical address of the device.
+	DeviceType      cec.DeviceType      // The device type of the device.
+	VendorID        uint32              // The vendor ID of the device.
+}
+
+// Attaches a new device to the bus.
+func (b *Bus) Attach(c Config) *Device {
+	d := &Device{
+		bus:      b,
+		addr:     c.LogicalAddr,
+		physAddr: c.PhysicalAddress,
+		typ:      c.DeviceType,
+		vendorID: c.VendorID,
+		done:     make(chan struct{}),
+	}
+	d.queue, d.in = queue.New(d.done)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.devices = append(b.devices, d)
+	return d
+}
+
+// Pauses the bus. All transmissions started while the bus is paused wait until Resume is called.
+// This allows tests to simulate devices that start transmitting at the same time.
+func (b *Bus) Pause() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.paused = true
+}
+
+// Resumes the bus. Pending transmissions go through arbitration: The frame with the lowest bit
+// pattern wins, i.e. the one with the lowest initiator address, and is delivered. All other
+// transmissions fail with TxArbitrationLost and aren't delivered.
+func (b *Bus) Resume() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.paused = false
+	if len(b.pending) == 0 {
+		return
+	}
+	winner := b.pending[0]
+	for _, t := range b.pending[1:] {
+		if bytes.Compare(t.frame(), winner.frame()) < 0 {
+			winner = t
+		}
+	}
+	for _, t := range b.pending {
+		if t == winner {
+			t.result <- b.deliver(t.sender, t.p)
+		} else {
+			t.result <- cec.TransmitError{Status: cec.TxArbitrationLost}
+		}
+	}
+	b.pending = nil
+}
+
+// Returns all frames transmitted on the bus in order, regardless of whether they were
+// acknowledged.
+func (b *Bus) Transmitted() []cec.Packet {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return append([]cec.Packet{}, b.frames...)
+}
+
+func (b *Bus) transmit(sender *Device, p cec.Packet) error {
+	b.mtx.Lock()
+	if !b.paused {
+		defer b.mtx.Unlock()
+		return b.deliver(sender, p)
+	}
+	t := &transmission{sender, p, make(chan error, 1)}
+	b.pending = append(b.pending, t)
+	b.mtx.Unlock()
+	return <-t.result
+}
+
+// Delivers a packet to its follower or to all other devices if it's a broadcast. Must be called
+// with b.mtx held.
+func (b *Bus) deliver(sender *Device, p cec.Packet) error {
+	b.frames = append(b.frames, p)
+	acked := false
+	for _, d := range b.devices {
+		if d == sender {
+			continue
+		}
+		if p.Follower == cec.Broadcast || d.addr == p.Follower {
+			d.queue <- p
+			acked = true
+		}
+	}
+	if !acked && p.Follower != cec.Broadcast {
+		return cec.TransmitError{Status: cec.TxNack}
+	}
+	return nil
+}
+
+// Returns true if a device other than sender uses the logical address a.
+func (b *Bus) poll(sender *Device, a cec.LogicalAddr) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for _, d := range b.devices {
+		if d != sender && d.addr == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes d from the bus.
+func (b *Bus) detach(d *Device) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for i, x := range b.devices {
+		if x == d {
+			b.devices = append(b.devices[:i], b.devices[i+1:]...)
+			return
+		}
+	}
+}
+
+// Device is a device attached to a virtual bus.
+type Device struct {
+	bus       *Bus
+	physAddr  cec.PhysicalAddress
+	typ       cec.DeviceType
+	vendorID  uint32
+	queue     chan<- cec.Packet
+	in        <-chan cec.Packet
+	done      chan struct{}
+	closeOnce sync.Once
+
+	addr cec.LogicalAddr // Guarded by bus.mtx.
+}
+
+// Detaches the device from the bus. This also closes the Receive channel.
+func (d *Device) Close() error {
+	err := errors.New("device already closed")
+	d.closeOnce.Do(func() {
+		d.bus.detach(d)
+		close(d.done)
+		err = nil
+	})
+	return err
+}
+
+func (d *Device) Receive() <-chan cec.Packet {
+	return d.in
+}
+
+func (d *Device) Send(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
+	d.SendStatus(follower, op, payload)
+}
+
+func (d *Device) Reply(follower cec.LogicalAddr, op cec.OpCode, payload []byte) {
+	d.SendStatus(follower, op, payload)
+}
+
+func (d *Device) SendStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	return d.bus.transmit(d, cec.Packet{
+		Initiator: d.GetLogicalAddress(),
+		Follower:  follower,
+		Op:        op,
+		Data:      payload,
+	})
+}
+
+func (d *Device) ReplyStatus(follower cec.LogicalAddr, op cec.OpCode, payload []byte) error {
+	return d.SendStatus(follower, op, payload)
+}
+
+// Returns true if another device on the bus uses the logical address a.
+func (d *Device) PollLogicalAddress(a cec.LogicalAddr) (bool, error) {
+	return d.bus.poll(d, a), nil
+}
+
+func (d *Device) ClaimLogicalAddress(a cec.LogicalAddr) error {
+	d.bus.mtx.Lock()
+	defer d.bus.mtx.Unlock()
+	d.addr = a
+	return nil
+}
+
+func (d *Device) GetVendorID() uint32 {
+	return d.vendorID
+}
+
+func (d *Device) GetDeviceType() cec.DeviceType {
+	return d.typ
+}
+
+func (d *Device) GetPhysicalAddress() cec.PhysicalAddress {
+	return d.physAddr
+}
+
+func (d *Device) GetLogicalAddress() cec.LogicalAddr {
+	d.bus.mtx.Lock()
+	defer d.bus.mtx.Unlock()
+	return d.addr
+}
@@ -0,0 +1,188 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtual
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec"
+)
+
+func attach(b *Bus, addr cec.LogicalAddr, physAddr cec.PhysicalAddress, typ cec.DeviceType) *Device {
+	return b.Attach(Config{
+		LogicalAddr:     addr,
+		PhysicalAddress: physAddr,
+		DeviceType:      typ,
+	})
+}
+
+func TestDelivery(t *testing.T) {
+	b := NewBus()
+	tv := attach(b, cec.TV, 0x0000, cec.DeviceTypeTV)
+	audio := attach(b, cec.AudioSystem, 0x1000, cec.DeviceTypeAudio)
+	player := attach(b, cec.Playback1, 0x2000, cec.DeviceTypePlayback)
+
+	if err := player.SendStatus(cec.TV, cec.OpImageViewOn, nil); err != nil {
+		t.Errorf("Failed to send direct packet: %s", err)
+	}
+	if err := player.SendStatus(cec.Broadcast, cec.OpActiveSource, []byte{0x20, 0x00}); err != nil {
+		t.Errorf("Failed to send broadcast: %s", err)
+	}
+	nack := cec.TransmitError{Status: cec.TxNack}
+	if err := player.SendStatus(cec.Rec1, cec.OpStandby, nil); err != nack {
+		t.Errorf("Expected %v for absent follower, got %v", nack, err)
+	}
+
+	direct := cec.Packet{Initiator: cec.Playback1, Follower: cec.TV, Op: cec.OpImageViewOn}
+	broadcast := cec.Packet{Initiator: cec.Playback1, Follower: cec.Broadcast, Op: cec.OpActiveSource, Data: []byte{0x20, 0x00}}
+	if diff := cmp.Diff([]cec.Packet{<-tv.Receive(), <-tv.Receive()}, []cec.Packet{direct, broadcast}); diff != "" {
+		t.Errorf("Unexpected packets received by TV: %s", diff)
+	}
+	if diff := cmp.Diff(<-audio.Receive(), broadcast); diff != "" {
+		t.Errorf("Unexpected packet received by audio system: %s", diff)
+	}
+	select {
+	case p := <-player.Receive():
+		t.Errorf("Sender received its own packet %s", p)
+	default:
+	}
+
+	expected := []cec.Packet{direct, broadcast, {Initiator: cec.Playback1, Follower: cec.Rec1, Op: cec.OpStandby}}
+	if diff := cmp.Diff(b.Transmitted(), expected); diff != "" {
+		t.Errorf("Unexpected transmitted frames: %s", diff)
+	}
+}
+
+func TestArbitration(t *testing.T) {
+	b := NewBus()
+	tv := attach(b, cec.TV, 0x0000, cec.DeviceTypeTV)
+	audio := attach(b, cec.AudioSystem, 0x1000, cec.DeviceTypeAudio)
+	player := attach(b, cec.Playback1, 0x2000, cec.DeviceTypePlayback)
+
+	b.Pause()
+	var wg sync.WaitGroup
+	devices := []*Device{audio, player, tv}
+	errs := make([]error, len(devices))
+	for i, d := range devices {
+		wg.Add(1)
+		go func(i int, d *Device) {
+			defer wg.Done()
+			errs[i] = d.SendStatus(cec.Broadcast, cec.OpStandby, nil)
+		}(i, d)
+	}
+	// Wait until all devices started transmitting.
+	for {
+		b.mtx.Lock()
+		n := len(b.pending)
+		b.mtx.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	b.Resume()
+	wg.Wait()
+
+	// The lowest initiator address wins arbitration, the others lose and aren't transmitted.
+	lost := cec.TransmitError{Status: cec.TxArbitrationLost}
+	if diff := cmp.Diff(errs, []error{lost, lost, nil}); diff != "" {
+		t.Errorf("Unexpected transmit results: %s", diff)
+	}
+	expected := []cec.Packet{
+		{Initiator: cec.TV, Follower: cec.Broadcast, Op: cec.OpStandby},
+	}
+	if diff := cmp.Diff(b.Transmitted(), expected); diff != "" {
+		t.Errorf("Unexpected transmitted frames: %s", diff)
+	}
+}
+
+func TestClaimLogicalAddress(t *testing.T) {
+	b := NewBus()
+	attach(b, cec.TV, 0x0000, cec.DeviceTypeTV)
+	attach(b, cec.Playback1, 0x1000, cec.DeviceTypePlayback)
+	d := attach(b, cec.Unregistered, 0x2000, cec.DeviceTypePlayback)
+
+	addr, err := cec.ClaimLogicalAddress(d, cec.DeviceTypePlayback)
+	if err != nil {
+		t.Fatalf("Failed to claim logical address: %s", err)
+	}
+	if addr != cec.Playback2 {
+		t.Errorf("Expected %s, got %s", cec.Playback2, addr)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := NewBus()
+	tv := attach(b, cec.TV, 0x0000, cec.DeviceTypeTV)
+	player := attach(b, cec.Playback1, 0x1000, cec.DeviceTypePlayback)
+
+	if err := tv.Close(); err != nil {
+		t.Errorf("Failed to close device: %s", err)
+	}
+	if _, ok := <-tv.Receive(); ok {
+		t.Errorf("Expected receive channel to be closed.")
+	}
+	nack := cec.TransmitError{Status: cec.TxNack}
+	if err := player.SendStatus(cec.TV, cec.OpStandby, nil); err != nack {
+		t.Errorf("Expected %v for detached follower, got %v", nack, err)
+	}
+	if err := tv.Close(); err == nil {
+		t.Errorf("Expected error when closing twice, but succeeded.")
+	}
+}
+
+// Runs a TV, a soundbar and a player on the same bus.
+func TestScenario(t *testing.T) {
+	b := NewBus()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	start := func(addr cec.LogicalAddr, physAddr cec.PhysicalAddress, typ cec.DeviceType, name string) *cec.Cec {
+		x, err := cec.New(attach(b, addr, physAddr, typ), cec.Config{OSDName: name, Logger: logger})
+		if err != nil {
+			t.Fatalf("Error setting up %s", err)
+		}
+		x.AddHandler(cec.DefaultHandler{})
+		go x.Run()
+		t.Cleanup(func() { x.Close() })
+		return x
+	}
+	start(cec.TV, 0x0000, cec.DeviceTypeTV, "tv")
+	start(cec.AudioSystem, 0x1000, cec.DeviceTypeAudio, "soundbar")
+	player := start(cec.Playback1, 0x1100, cec.DeviceTypePlayback, "player")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := player.Request(ctx, cec.AudioSystem, cec.GiveOSDName{})
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	if diff := cmp.Diff(resp, cec.SetOSDName{Name: "soundbar"}); diff != "" {
+		t.Errorf("Unexpected response: %s", diff)
+	}
+
+	resp, err = player.Request(ctx, cec.TV, cec.GivePhysicalAddress{})
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	expected := cec.ReportPhysicalAddress{Addr: 0x0000, Type: cec.DeviceTypeTV}
+	if diff := cmp.Diff(resp, expected); diff != "" {
+		t.Errorf("Unexpected response: %s", diff)
+	}
+}
@@ -1,5 +1,5 @@
 module znkr.io/cec
 
-go 1.19
+go 1.21
 
 require github.com/google/go-cmp v0.5.9
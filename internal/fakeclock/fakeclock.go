@@ -0,0 +1,97 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakeclock provides a cec.Clock for tests that only advances when told to.
+package fakeclock
+
+import (
+	"sync"
+	"time"
+
+	"znkr.io/cec"
+)
+
+// Clock is a cec.Clock that only advances when Advance is called. Timers are called synchronously
+// by Advance.
+type Clock struct {
+	mtx    sync.Mutex
+	now    time.Duration
+	timers []*timer
+}
+
+type timer struct {
+	c       *Clock
+	at      time.Duration
+	f       func()
+	stopped bool
+}
+
+// Creates a new clock.
+func New() *Clock {
+	return &Clock{}
+}
+
+// Clock implements cec.Clock.
+func (c *Clock) AfterFunc(d time.Duration, f func()) cec.Timer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	t := &timer{c: c, at: c.now + d, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *timer) Stop() bool {
+	t.c.mtx.Lock()
+	defer t.c.mtx.Unlock()
+	stopped := t.stopped
+	t.stopped = true
+	return !stopped
+}
+
+// Advances the clock by d and calls all timers that are due in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	end := c.now + d
+	c.mtx.Unlock()
+	for {
+		c.mtx.Lock()
+		var next *timer
+		for _, t := range c.timers {
+			if !t.stopped && t.at <= end && (next == nil || t.at < next.at) {
+				next = t
+			}
+		}
+		if next == nil {
+			c.now = end
+			c.timers = pending(c.timers)
+			c.mtx.Unlock()
+			return
+		}
+		c.now = next.at
+		next.stopped = true
+		c.mtx.Unlock()
+		next.f()
+	}
+}
+
+// Returns the timers that weren't called or stopped yet.
+func pending(timers []*timer) []*timer {
+	var p []*timer
+	for _, t := range timers {
+		if !t.stopped {
+			p = append(p, t)
+		}
+	}
+	return p
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package queue provides an unbounded packet queue for device implementations.
+//
+// Devices that wait for transmit results on the same path that delivers received packets must
+// never block on a slow consumer of the receive channel. Otherwise, a handler that sends a message
+// while the next packet is being delivered deadlocks.
+package queue
+
+import "znkr.io/cec"
+
+// Creates a new queue. Packets sent to in are delivered to out in order. Closing in closes out
+// once all queued packets are delivered. Closing done stops delivery immediately and closes out.
+func New(done <-chan struct{}) (in chan<- cec.Packet, out <-chan cec.Packet) {
+	i := make(chan cec.Packet)
+	o := make(chan cec.Packet)
+	go run(i, o, done)
+	return i, o
+}
+
+func run(in <-chan cec.Packet, out chan<- cec.Packet, done <-chan struct{}) {
+	defer close(out)
+	var queue []cec.Packet
+	for in != nil || len(queue) > 0 {
+		// Only try to deliver if there is something to deliver, a nil channel blocks forever.
+		var o chan<- cec.Packet
+		var next cec.Packet
+		if len(queue) > 0 {
+			o = out
+			next = queue[0]
+		}
+		select {
+		case p, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			queue = append(queue, p)
+		case o <- next:
+			queue = queue[1:]
+		case <-done:
+			return
+		}
+	}
+}
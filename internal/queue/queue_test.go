@@ -0,0 +1,53 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec"
+)
+
+func TestQueue(t *testing.T) {
+	in, out := New(make(chan struct{}))
+
+	// Sending must not block even though nobody is receiving yet.
+	var expected []cec.Packet
+	for i := 0; i < 100; i++ {
+		p := cec.Packet{Initiator: cec.TV, Follower: cec.Playback1, Op: cec.OpCode(i)}
+		in <- p
+		expected = append(expected, p)
+	}
+	close(in)
+
+	var actual []cec.Packet
+	for p := range out {
+		actual = append(actual, p)
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestQueue_Done(t *testing.T) {
+	done := make(chan struct{})
+	in, out := New(done)
+	in <- cec.Packet{}
+	close(done)
+	for range out {
+		// Queued packets may or may not be delivered, but out must be closed eventually.
+	}
+}
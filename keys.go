@@ -0,0 +1,240 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// A held key is considered released if no repeat arrives within this time. This is the
+	// follower safety timeout of the CEC specification.
+	KeyReleaseTimeout = 550 * time.Millisecond
+
+	// The interval in which a KeySender repeats a held key. The CEC specification requires repeats
+	// within KeyReleaseTimeout.
+	KeyRepeatInterval = 450 * time.Millisecond
+)
+
+// A Clock schedules functions. It's used by timing sensitive components and can be replaced in
+// tests.
+type Clock interface {
+	// Calls f in its own goroutine after d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// A Timer is a function scheduled by a Clock.
+type Timer interface {
+	// Stops the timer. Returns false if the function was already called or the timer was stopped.
+	Stop() bool
+}
+
+// SystemClock is a Clock based on the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// The type of a KeyEvent.
+type KeyEventType int
+
+const (
+	KeyDown   KeyEventType = iota // A key was pressed.
+	KeyRepeat                     // A held key was repeated.
+	KeyUp                         // A key was released.
+)
+
+// A KeyEvent describes a change of a remote control key.
+type KeyEvent struct {
+	Type      KeyEventType
+	Key       UserControl
+	Initiator LogicalAddr // The device that sent the key.
+}
+
+// The KeyHandler turns UserControlPressed and UserControlReleased messages into KeyEvents. Only
+// one key can be held at a time: Pressing another key releases the held key first. A
+// UserControlReleased releases the held key, and a held key is also released if it's not repeated
+// within KeyReleaseTimeout.
+type KeyHandler struct {
+	clock Clock
+	f     func(KeyEvent)
+
+	mtx   sync.Mutex
+	held  bool
+	key   KeyEvent // The held key, valid if held is true.
+	timer Timer
+	gen   int // Incremented whenever the release timer is replaced.
+}
+
+// Creates a new KeyHandler that calls f for every KeyEvent. The calls are serialized, f must not
+// block. If clock is nil, SystemClock is used.
+func NewKeyHandler(clock Clock, f func(KeyEvent)) *KeyHandler {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &KeyHandler{
+		clock: clock,
+		f:     f,
+	}
+}
+
+// KeyHandler implements Handler.
+func (h *KeyHandler) HandleMessage(x *Cec, msg Message) bool {
+	switch cmd := msg.Cmd.(type) {
+	case UserControlPressed:
+		h.press(msg.Initiator, cmd.Pressed)
+		return true
+	case UserControlReleased:
+		h.release()
+		return true
+	}
+	return false
+}
+
+func (h *KeyHandler) press(initiator LogicalAddr, key UserControl) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.held && h.key.Key == key && h.key.Initiator == initiator {
+		h.f(KeyEvent{Type: KeyRepeat, Key: key, Initiator: initiator})
+	} else {
+		h.releaseLocked()
+		h.held = true
+		h.key = KeyEvent{Key: key, Initiator: initiator}
+		h.f(KeyEvent{Type: KeyDown, Key: key, Initiator: initiator})
+	}
+
+	h.stopTimerLocked()
+	gen := h.gen
+	h.timer = h.clock.AfterFunc(KeyReleaseTimeout, func() {
+		h.mtx.Lock()
+		defer h.mtx.Unlock()
+		// The timer might have fired while it was replaced.
+		if h.gen == gen {
+			h.releaseLocked()
+		}
+	})
+}
+
+func (h *KeyHandler) release() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.stopTimerLocked()
+	h.releaseLocked()
+}
+
+func (h *KeyHandler) stopTimerLocked() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	h.gen++
+}
+
+func (h *KeyHandler) releaseLocked() {
+	if !h.held {
+		return
+	}
+	h.held = false
+	h.f(KeyEvent{Type: KeyUp, Key: h.key.Key, Initiator: h.key.Initiator})
+}
+
+// A KeySender sends key presses to another device. While a key is held, it's repeated every
+// KeyRepeatInterval until it's released.
+type KeySender struct {
+	x     *Cec
+	clock Clock
+
+	mtx      sync.Mutex
+	held     bool
+	follower LogicalAddr
+	key      UserControl
+	timer    Timer
+	gen      int // Incremented whenever the repeat timer is replaced.
+}
+
+// Creates a new KeySender. If clock is nil, SystemClock is used.
+func NewKeySender(x *Cec, clock Clock) *KeySender {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &KeySender{
+		x:     x,
+		clock: clock,
+	}
+}
+
+// Presses key on follower and holds it until Release is called. A key that is already held is
+// released first.
+func (s *KeySender) Press(follower LogicalAddr, key UserControl) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.releaseLocked(); err != nil {
+		return err
+	}
+	if err := s.x.Send(follower, UserControlPressed{Pressed: key}); err != nil {
+		return err
+	}
+	s.held, s.follower, s.key = true, follower, key
+	s.scheduleLocked()
+	return nil
+}
+
+// Releases the held key. Does nothing if no key is held.
+func (s *KeySender) Release() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.releaseLocked()
+}
+
+func (s *KeySender) releaseLocked() error {
+	if !s.held {
+		return nil
+	}
+	s.held = false
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.gen++
+	return s.x.Send(s.follower, UserControlReleased{})
+}
+
+func (s *KeySender) scheduleLocked() {
+	gen := s.gen
+	s.timer = s.clock.AfterFunc(KeyRepeatInterval, func() {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		// The key might have been released while the timer fired.
+		if !s.held || s.gen != gen {
+			return
+		}
+		if err := s.x.Send(s.follower, UserControlPressed{Pressed: s.key}); err != nil {
+			// The follower is gone, there is no point in releasing the key.
+			s.x.log.Warn("Failed to repeat key",
+				slog.String("follower", s.follower.String()),
+				slog.String("key", s.key.String()),
+				slog.Any("error", err))
+			s.held = false
+			s.timer = nil
+			return
+		}
+		s.scheduleLocked()
+	})
+}
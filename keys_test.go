@@ -0,0 +1,232 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+	"znkr.io/cec/internal/fakeclock"
+
+	. "znkr.io/cec"
+)
+
+func TestKeyHandler(t *testing.T) {
+	pressed := func(k UserControl) Message {
+		return Message{Initiator: TV, Follower: Playback1, Cmd: UserControlPressed{Pressed: k}}
+	}
+	released := Message{Initiator: TV, Follower: Playback1, Cmd: UserControlReleased{}}
+	// Either a message or a clock advance.
+	type step struct {
+		msg     Message
+		advance time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []KeyEvent
+	}{
+		{
+			name: "press_release",
+			steps: []step{
+				{msg: pressed(UcSelect)},
+				{advance: 100 * time.Millisecond},
+				{msg: released},
+				{advance: time.Second},
+			},
+			want: []KeyEvent{
+				{KeyDown, UcSelect, TV},
+				{KeyUp, UcSelect, TV},
+			},
+		}, {
+			name: "hold",
+			steps: []step{
+				{msg: pressed(UcUp)},
+				{advance: 450 * time.Millisecond},
+				{msg: pressed(UcUp)},
+				{advance: 450 * time.Millisecond},
+				{msg: pressed(UcUp)},
+				{advance: 100 * time.Millisecond},
+				{msg: released},
+			},
+			want: []KeyEvent{
+				{KeyDown, UcUp, TV},
+				{KeyRepeat, UcUp, TV},
+				{KeyRepeat, UcUp, TV},
+				{KeyUp, UcUp, TV},
+			},
+		}, {
+			name: "release_timeout",
+			steps: []step{
+				{msg: pressed(UcUp)},
+				{advance: 450 * time.Millisecond},
+				{msg: pressed(UcUp)},
+				{advance: 549 * time.Millisecond},
+				{msg: pressed(UcUp)},
+				{advance: 550 * time.Millisecond},
+				// The release after the timeout is ignored.
+				{msg: released},
+			},
+			want: []KeyEvent{
+				{KeyDown, UcUp, TV},
+				{KeyRepeat, UcUp, TV},
+				{KeyRepeat, UcUp, TV},
+				{KeyUp, UcUp, TV},
+			},
+		}, {
+			name: "press_after_timeout",
+			steps: []step{
+				{msg: pressed(UcUp)},
+				{advance: time.Second},
+				{msg: pressed(UcUp)},
+				{msg: released},
+			},
+			want: []KeyEvent{
+				{KeyDown, UcUp, TV},
+				{KeyUp, UcUp, TV},
+				{KeyDown, UcUp, TV},
+				{KeyUp, UcUp, TV},
+			},
+		}, {
+			name: "other_key",
+			steps: []step{
+				{msg: pressed(UcUp)},
+				{msg: pressed(UcDown)},
+				{msg: released},
+			},
+			want: []KeyEvent{
+				{KeyDown, UcUp, TV},
+				{KeyUp, UcUp, TV},
+				{KeyDown, UcDown, TV},
+				{KeyUp, UcDown, TV},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := fakeclock.New()
+			var events []KeyEvent
+			h := NewKeyHandler(clock, func(e KeyEvent) { events = append(events, e) })
+			for _, s := range test.steps {
+				if s.msg.Cmd != nil {
+					if !h.HandleMessage(nil, s.msg) {
+						t.Errorf("Message %v wasn't handled", s.msg)
+					}
+				}
+				clock.Advance(s.advance)
+			}
+			if diff := cmp.Diff(events, test.want); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.want, events, diff)
+			}
+		})
+	}
+}
+
+func TestKeyHandler_Cec(t *testing.T) {
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	var events []KeyEvent
+	c.AddHandler(NewKeyHandler(fakeclock.New(), func(e KeyEvent) { events = append(events, e) }))
+	in := []Packet{
+		{TV, Playback1, OpUserControlPressed, []byte{byte(UcUp)}},
+		// Releases have no operand.
+		{TV, Playback1, OpUserControlReleased, nil},
+	}
+	if actual := d.Run(in, func() { c.Run() }); len(actual) != 0 {
+		t.Errorf("Expected no response, got %v", actual)
+	}
+	// The clock never advances, the release can't be caused by the timeout.
+	want := []KeyEvent{
+		{KeyDown, UcUp, TV},
+		{KeyUp, UcUp, TV},
+	}
+	if diff := cmp.Diff(events, want); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", want, events, diff)
+	}
+}
+
+func TestKeySender(t *testing.T) {
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	clock := fakeclock.New()
+	s := NewKeySender(c, clock)
+	actual := d.Run(nil, func() {
+		if err := s.Press(TV, UcUp); err != nil {
+			t.Errorf("Press failed: %s", err)
+		}
+		clock.Advance(1000 * time.Millisecond)
+		// Pressing another key releases the first one.
+		if err := s.Press(TV, UcDown); err != nil {
+			t.Errorf("Press failed: %s", err)
+		}
+		clock.Advance(100 * time.Millisecond)
+		if err := s.Release(); err != nil {
+			t.Errorf("Release failed: %s", err)
+		}
+		// No repeats after the release.
+		clock.Advance(time.Second)
+		if err := s.Release(); err != nil {
+			t.Errorf("Release failed: %s", err)
+		}
+	})
+	expected := []Packet{
+		{Playback1, TV, OpUserControlPressed, []byte{byte(UcUp)}},
+		{Playback1, TV, OpUserControlPressed, []byte{byte(UcUp)}},
+		{Playback1, TV, OpUserControlPressed, []byte{byte(UcUp)}},
+		{Playback1, TV, OpUserControlReleased, []byte{}},
+		{Playback1, TV, OpUserControlPressed, []byte{byte(UcDown)}},
+		{Playback1, TV, OpUserControlReleased, []byte{}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestKeySender_Nack(t *testing.T) {
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	clock := fakeclock.New()
+	s := NewKeySender(c, clock)
+	actual := d.Run(nil, func() {
+		if err := s.Press(TV, UcUp); err != nil {
+			t.Errorf("Press failed: %s", err)
+		}
+		// The TV disappears, the repeat fails and stops the sender.
+		d.SetTxStatus(TV, TxNack)
+		clock.Advance(time.Second)
+		if err := s.Release(); err != nil {
+			t.Errorf("Release failed: %s", err)
+		}
+	})
+	expected := []Packet{
+		{Playback1, TV, OpUserControlPressed, []byte{byte(UcUp)}},
+		{Playback1, TV, OpUserControlPressed, []byte{byte(UcUp)}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import "sync"
+
+// The MenuHandler implements Device Menu Control. It answers MenuRequest and tracks whether the
+// menu of this device is active. All other messages are passed on to a wrapped handler, e.g. a
+// KeyHandler, except navigation keys while the menu isn't active.
+type MenuHandler struct {
+	h        Handler
+	onChange func(active bool)
+
+	mtx      sync.Mutex
+	active   bool
+	rejected bool // Whether the last key press was rejected.
+}
+
+// Creates a new MenuHandler wrapping h. If h is nil, no other messages are handled. The menu is
+// inactive initially. If onChange isn't nil, it's called whenever another device activates or
+// deactivates the menu.
+func NewMenuHandler(h Handler, onChange func(active bool)) *MenuHandler {
+	return &MenuHandler{
+		h:        h,
+		onChange: onChange,
+	}
+}
+
+// Returns true if the menu is active.
+func (m *MenuHandler) Active() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.active
+}
+
+// Activates or deactivates the menu, e.g. because the user closed it, and reports the change to
+// the TV.
+func (m *MenuHandler) SetActive(x *Cec, active bool) error {
+	if !m.set(active) {
+		return nil
+	}
+	return x.Send(TV, MenuStatus{State: menuState(active)})
+}
+
+// Sets the menu state and returns true if it changed.
+func (m *MenuHandler) set(active bool) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	changed := m.active != active
+	m.active = active
+	return changed
+}
+
+func menuState(active bool) MenuState {
+	if active {
+		return MenuActivated
+	}
+	return MenuDeactivated
+}
+
+// Remembers whether a key press was rejected, so that its release can be rejected as well.
+func (m *MenuHandler) reject(rejected bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.rejected = rejected
+}
+
+// Returns true if a release belongs to a rejected key press. A release has no operand, it always
+// belongs to the last key press.
+func (m *MenuHandler) isRejectedRelease() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	rejected := m.rejected
+	m.rejected = false
+	return rejected
+}
+
+// Returns true if c is used to navigate a menu.
+func isNavigationKey(c UserControl) bool {
+	switch {
+	case c <= UcExit:
+		return true
+	case UcNumber0 <= c && c <= UcClear:
+		return true
+	case c == UcPageUp || c == UcPageDown:
+		return true
+	}
+	return false
+}
+
+// MenuHandler implements Handler.
+func (m *MenuHandler) HandleMessage(x *Cec, msg Message) bool {
+	switch cmd := msg.Cmd.(type) {
+	case MenuRequest:
+		switch cmd.Type {
+		case MenuRequestActivate, MenuRequestDeactivate:
+			active := cmd.Type == MenuRequestActivate
+			if m.set(active) && m.onChange != nil {
+				m.onChange(active)
+			}
+		case MenuRequestQuery:
+		default:
+			x.Reply(msg.Initiator, FeatureAbort{Abort: cmd.Op(), Reason: AbortInvalidOperand})
+			return true
+		}
+		x.Reply(msg.Initiator, MenuStatus{State: menuState(m.Active())})
+		return true
+
+	case UserControlPressed:
+		rejected := isNavigationKey(cmd.Pressed) && !m.Active()
+		m.reject(rejected)
+		if rejected {
+			return false
+		}
+
+	case UserControlReleased:
+		if m.isRejectedRelease() {
+			return false
+		}
+	}
+	if m.h == nil {
+		return false
+	}
+	return m.h.HandleMessage(x, msg)
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestMenuHandler(t *testing.T) {
+	selectKey := Packet{TV, Playback1, OpUserControlPressed, []byte{byte(UcSelect)}}
+	playKey := Packet{TV, Playback1, OpUserControlPressed, []byte{byte(UcPlay)}}
+	release := Packet{TV, Playback1, OpUserControlReleased, nil}
+	tests := []struct {
+		name     string
+		in       []Packet
+		out      []Packet
+		keys     []UserControl // Pressed keys passed on to the wrapped handler.
+		releases int           // Releases passed on to the wrapped handler.
+		changes  []bool
+		active   bool
+	}{
+		{
+			name: "query",
+			in: []Packet{
+				{TV, Playback1, OpMenuRequest, []byte{byte(MenuRequestQuery)}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpMenuStatus, []byte{byte(MenuDeactivated)}},
+			},
+		}, {
+			name: "activate",
+			in: []Packet{
+				{TV, Playback1, OpMenuRequest, []byte{byte(MenuRequestActivate)}},
+				selectKey,
+				playKey,
+			},
+			out: []Packet{
+				{Playback1, TV, OpMenuStatus, []byte{byte(MenuActivated)}},
+			},
+			keys:    []UserControl{UcSelect, UcPlay},
+			changes: []bool{true},
+			active:  true,
+		}, {
+			name: "deactivate",
+			in: []Packet{
+				{TV, Playback1, OpMenuRequest, []byte{byte(MenuRequestActivate)}},
+				{TV, Playback1, OpMenuRequest, []byte{byte(MenuRequestActivate)}},
+				{TV, Playback1, OpMenuRequest, []byte{byte(MenuRequestDeactivate)}},
+				selectKey,
+			},
+			out: []Packet{
+				{Playback1, TV, OpMenuStatus, []byte{byte(MenuActivated)}},
+				{Playback1, TV, OpMenuStatus, []byte{byte(MenuActivated)}},
+				{Playback1, TV, OpMenuStatus, []byte{byte(MenuDeactivated)}},
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpUserControlPressed), byte(AbortUnrecognizedOpCode)}},
+			},
+			changes: []bool{true, false},
+		}, {
+			// Navigation keys are rejected while the menu is inactive, other keys are passed on.
+			name: "inactive",
+			in:   []Packet{selectKey, release, playKey, release},
+			out: []Packet{
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpUserControlPressed), byte(AbortUnrecognizedOpCode)}},
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpUserControlReleased), byte(AbortUnrecognizedOpCode)}},
+			},
+			keys:     []UserControl{UcPlay},
+			releases: 1,
+		}, {
+			name: "invalid_request",
+			in: []Packet{
+				{TV, Playback1, OpMenuRequest, []byte{0x03}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpFeatureAbort, []byte{byte(OpMenuRequest), byte(AbortInvalidOperand)}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var keys []UserControl
+			var releases int
+			var changes []bool
+			m := NewMenuHandler(HandlerFunc(func(x *Cec, msg Message) bool {
+				switch cmd := msg.Cmd.(type) {
+				case UserControlPressed:
+					keys = append(keys, cmd.Pressed)
+					return true
+				case UserControlReleased:
+					releases++
+					return true
+				}
+				return false
+			}), func(active bool) { changes = append(changes, active) })
+			d := fake.New(Playback1, DeviceTypePlayback)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(m)
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+			if diff := cmp.Diff(keys, test.keys); diff != "" {
+				t.Errorf("Expected keys %v, got %v: %s", test.keys, keys, diff)
+			}
+			if releases != test.releases {
+				t.Errorf("Expected %d releases, got %d", test.releases, releases)
+			}
+			if diff := cmp.Diff(changes, test.changes); diff != "" {
+				t.Errorf("Expected changes %v, got %v: %s", test.changes, changes, diff)
+			}
+			if m.Active() != test.active {
+				t.Errorf("Expected menu active to be %t, got %t", test.active, m.Active())
+			}
+		})
+	}
+}
+
+func TestMenuHandler_NilHandler(t *testing.T) {
+	m := NewMenuHandler(nil, nil)
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	c.AddHandler(m)
+	in := []Packet{
+		{TV, Playback1, OpMenuRequest, []byte{byte(MenuRequestActivate)}},
+		{TV, Playback1, OpUserControlPressed, []byte{byte(UcSelect)}},
+	}
+	actual := d.Run(in, func() { c.Run() })
+	expected := []Packet{
+		{Playback1, TV, OpMenuStatus, []byte{byte(MenuActivated)}},
+		{Playback1, TV, OpFeatureAbort, []byte{byte(OpUserControlPressed), byte(AbortUnrecognizedOpCode)}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestMenuHandler_SetActive(t *testing.T) {
+	m := NewMenuHandler(UnhandledHandler{}, nil)
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	actual := d.Run(nil, func() {
+		for _, active := range []bool{true, true, false} {
+			if err := m.SetActive(c, active); err != nil {
+				t.Errorf("SetActive(%t) failed: %s", active, err)
+			}
+		}
+	})
+	expected := []Packet{
+		{Playback1, TV, OpMenuStatus, []byte{byte(MenuActivated)}},
+		{Playback1, TV, OpMenuStatus, []byte{byte(MenuDeactivated)}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+// Returns the menu language of this device, empty if it's unknown. It's initialized from
+// Config.MenuLanguage and follows the menu language broadcast by the TV.
+func (x *Cec) MenuLanguage() string {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+	return x.lang
+}
+
+// Follows menu language changes broadcast by the TV.
+func (x *Cec) trackMenuLanguage(msg Message) {
+	cmd, ok := msg.Cmd.(SetMenuLanguage)
+	if !ok || msg.Initiator != TV {
+		return
+	}
+	x.mtx.Lock()
+	changed := x.lang != cmd.Language
+	x.lang = cmd.Language
+	x.mtx.Unlock()
+	if changed && x.onLang != nil {
+		x.onLang(cmd.Language)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestMenuLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		in      []Packet
+		out     []Packet
+		changes []string
+		want    string
+	}{
+		{
+			name: "get_menu_language",
+			lang: "deu",
+			in: []Packet{
+				{TV, AudioSystem, OpGetMenuLanguage, nil},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpSetMenuLanguage, []byte("deu")},
+			},
+			want: "deu",
+		}, {
+			// Without a menu language, the request is aborted.
+			name: "get_menu_language_unknown",
+			in: []Packet{
+				{TV, AudioSystem, OpGetMenuLanguage, nil},
+			},
+			out: []Packet{
+				{AudioSystem, TV, OpFeatureAbort, []byte{byte(OpGetMenuLanguage), byte(AbortUnrecognizedOpCode)}},
+			},
+		}, {
+			name: "tv_changes_language",
+			lang: "deu",
+			in: []Packet{
+				{TV, Broadcast, OpSetMenuLanguage, []byte("eng")},
+				{TV, Broadcast, OpSetMenuLanguage, []byte("eng")},
+				{TV, AudioSystem, OpGetMenuLanguage, nil},
+			},
+			out: []Packet{
+				{AudioSystem, Broadcast, OpSetMenuLanguage, []byte("eng")},
+			},
+			changes: []string{"eng"},
+			want:    "eng",
+		}, {
+			name: "tv_sends_upper_case",
+			lang: "deu",
+			in: []Packet{
+				{TV, Broadcast, OpSetMenuLanguage, []byte("ENG")},
+			},
+			out:     []Packet{},
+			changes: []string{"eng"},
+			want:    "eng",
+		}, {
+			// Only the TV sets the menu language.
+			name: "ignore_other_devices",
+			lang: "deu",
+			in: []Packet{
+				{Playback1, Broadcast, OpSetMenuLanguage, []byte("eng")},
+			},
+			out:  []Packet{},
+			want: "deu",
+		}, {
+			name: "ignore_invalid_language",
+			lang: "deu",
+			in: []Packet{
+				{TV, Broadcast, OpSetMenuLanguage, []byte("EN1")},
+			},
+			out:  []Packet{},
+			want: "deu",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var changes []string
+			d := fake.New(AudioSystem, DeviceTypeAudio)
+			c, err := New(d, Config{
+				OSDName:      "test",
+				Logger:       discard,
+				MenuLanguage: test.lang,
+				OnMenuLanguage: func(language string) {
+					changes = append(changes, language)
+				},
+			})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(DefaultHandler{})
+
+			out := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(test.out, out); diff != "" {
+				t.Errorf("Unexpected packets (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.changes, changes); diff != "" {
+				t.Errorf("Unexpected language changes (-want +got):\n%s", diff)
+			}
+			if got := c.MenuLanguage(); got != test.want {
+				t.Errorf("MenuLanguage() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInvalidMenuLanguage(t *testing.T) {
+	_, err := New(fake.New(AudioSystem, DeviceTypeAudio), Config{
+		OSDName:      "test",
+		MenuLanguage: "english",
+	})
+	if _, ok := err.(InvalidLanguage); !ok {
+		t.Errorf("Expected InvalidLanguage error, got %v", err)
+	}
+}
@@ -16,6 +16,7 @@ package cec
 
 import (
 	"fmt"
+	"strings"
 )
 
 type IncorrectPacketDataLength struct {
@@ -33,6 +34,31 @@ func (e InvalidOSDName) Error() string {
 	return fmt.Sprintf("Invalid data for OSD name.")
 }
 
+// InvalidLanguage is returned for menu languages that aren't ISO 639-2 codes of three lower case
+// letters.
+type InvalidLanguage struct {
+	Language string
+}
+
+func (e InvalidLanguage) Error() string {
+	return fmt.Sprintf("Invalid menu language %q.", e.Language)
+}
+
+type InvalidOSDString struct{}
+
+func (e InvalidOSDString) Error() string {
+	return fmt.Sprintf("Invalid data for OSD string.")
+}
+
+// InvalidChannel is returned for channel numbers that can't be encoded, see Channel.
+type InvalidChannel struct {
+	Channel Channel
+}
+
+func (e InvalidChannel) Error() string {
+	return fmt.Sprintf("Invalid channel %+v.", e.Channel)
+}
+
 type InvalidVendorId struct{}
 
 func (e InvalidVendorId) Error() string {
@@ -47,6 +73,38 @@ func (e InvalidVolume) Error() string {
 	return fmt.Sprintf("Invalid volume: %d", e.volume)
 }
 
+// Closed is returned when the Cec was closed.
+type Closed struct{}
+
+func (e Closed) Error() string {
+	return fmt.Sprintf("CEC closed.")
+}
+
+// DeviceClosed is returned when the device stopped delivering messages.
+type DeviceClosed struct{}
+
+func (e DeviceClosed) Error() string {
+	return fmt.Sprintf("Device closed.")
+}
+
+type NotARequest struct {
+	op OpCode
+}
+
+func (e NotARequest) Error() string {
+	return fmt.Sprintf("%s doesn't have a response.", e.op)
+}
+
+// FeatureAbortError is returned when a request was answered with a FeatureAbort.
+type FeatureAbortError struct {
+	Op     OpCode      // The aborted opcode.
+	Reason AbortReason // The reason for the abort.
+}
+
+func (e FeatureAbortError) Error() string {
+	return fmt.Sprintf("Feature abort for %s: %s", e.Op, e.Reason)
+}
+
 // A Message is a representation of an HDMI CEC message.
 type Message struct {
 	Initiator LogicalAddr // The sender of this message.
@@ -138,12 +196,17 @@ type (
 		On bool
 	}
 
+	// Reports the system audio mode of this device. This is usually send in response to GiveSystemAudioModeStatus.
+	SystemAudioModeStatus struct {
+		On bool
+	}
+
 	// Requests the current AudioStatus from a device. This should be answered with a ReportAudioStatus command.
 	GiveAudioStatus struct {
 		emptyCommand
 	}
 
-	// Requests the system audio mode status. This should be answered with a SetSystemAudioMode command.
+	// Requests the system audio mode status. This should be answered with a SystemAudioModeStatus command.
 	GiveSystemAudioModeStatus struct {
 		emptyCommand
 	}
@@ -194,9 +257,10 @@ type (
 		Pressed UserControl // The control that was pressed.
 	}
 
-	// Reports that the user released a control.
+	// Reports that the user released the control that was last pressed. It has no operand, the
+	// release always belongs to the last UserControlPressed.
 	UserControlReleased struct {
-		Released UserControl // The control that was released.
+		emptyCommand
 	}
 
 	// Requests standby.
@@ -204,11 +268,131 @@ type (
 		emptyCommand
 	}
 
-	// TODO: Not yet implemented.
+	// Requests the menu language of a device. This should be answered with a broadcast
+	// SetMenuLanguage command.
+	GetMenuLanguage struct {
+		emptyCommand
+	}
+
+	// Sets the menu language of all devices. Broadcast by the TV, usually in response to
+	// GetMenuLanguage.
+	SetMenuLanguage struct {
+		Language string // The ISO 639-2 language code, e.g. "eng".
+	}
+
+	// Broadcast by a device to announce that it has started to use the bus as the active source.
 	ActiveSource struct {
+		Addr PhysicalAddress // The physical address of the new active source.
+	}
+
+	// Sent directly to the TV by the active source when it stops being the active source, e.g.
+	// when it goes to standby.
+	InactiveSource struct {
+		Addr PhysicalAddress // The physical address of the device that stopped being the active source.
+	}
+
+	// Requests the active source to announce itself with ActiveSource.
+	RequestActiveSource struct {
+		emptyCommand
+	}
+
+	// Broadcast by a switch when the routing changed, e.g. because the user selected a different
+	// input.
+	RoutingChange struct {
+		From PhysicalAddress // The physical address of the previously selected input.
+		To   PhysicalAddress // The physical address of the newly selected input.
+	}
+
+	// Broadcast by a switch in response to a RoutingChange to report the active route below it.
+	RoutingInformation struct {
+		Addr PhysicalAddress // The physical address of the active route.
+	}
+
+	// Broadcast by the TV to request a streaming path from the device at Addr.
+	SetStreamPath struct {
+		Addr PhysicalAddress // The physical address of the requested device.
+	}
+
+	// Turns on the TV and shows the active source. Any menu on the TV stays on screen.
+	ImageViewOn struct {
+		emptyCommand
+	}
+
+	// Turns on the TV and shows the active source. Any menu on the TV is removed.
+	TextViewOn struct {
+		emptyCommand
+	}
+
+	// Requests the deck status of a device. Depending on Request, this is answered with a single
+	// DeckStatus or with a DeckStatus on every change.
+	GiveDeckStatus struct {
+		Request StatusRequest
+	}
+
+	// Reports the deck status of a device. This is usually send in response to GiveDeckStatus.
+	DeckStatus struct {
+		Info DeckInfo
+	}
+
+	// Controls a deck, e.g. to stop playback or to skip.
+	DeckControl struct {
+		Mode DeckControlMode
+	}
+
+	// Starts playback on a device in the given mode, PlayStill pauses playback.
+	Play struct {
+		Mode PlayMode
+	}
+
+	// Requests a device to activate or deactivate its menu or to report whether it's active. This
+	// should be answered with MenuStatus.
+	MenuRequest struct {
+		Type MenuRequestType
+	}
+
+	// Reports whether the menu of a device is active. While the menu is active, the TV forwards
+	// remote control keys to the device.
+	MenuStatus struct {
+		State MenuState
+	}
+
+	// Displays a text message on the TV.
+	SetOSDString struct {
+		Control DisplayControl
+		Text    string // Between 1 and 13 ASCII characters.
+	}
+
+	// Tunes a tuner to an analog service and displays it.
+	SelectAnalogService struct {
+		Service AnalogService
+	}
+
+	// Tunes a tuner to a digital service and displays it.
+	SelectDigitalService struct {
+		Service DigitalService
+	}
+
+	// Tunes a tuner to the next service.
+	TunerStepIncrement struct {
 		emptyCommand
 	}
 
+	// Tunes a tuner to the previous service.
+	TunerStepDecrement struct {
+		emptyCommand
+	}
+
+	// Requests the status of a tuner. Depending on Request, this is answered with a single
+	// TunerDeviceStatus or with a TunerDeviceStatus on every change.
+	GiveTunerDeviceStatus struct {
+		Request StatusRequest
+	}
+
+	// Reports the status of a tuner. This is usually send in response to GiveTunerDeviceStatus.
+	TunerDeviceStatus struct {
+		Status TunerStatus
+	}
+
 	// TODO: Not yet implemented.
 	VendorCommandWithID struct {
 		emptyCommand
@@ -218,7 +402,70 @@ type (
 func unmarshalCommand(op OpCode, data []byte) (Command, error) {
 	switch op {
 	case OpActiveSource:
-		return ActiveSource{}, nil
+		if len(data) != 2 {
+			return nil, IncorrectPacketDataLength{2, len(data)}
+		}
+		return ActiveSource{
+			Addr: PhysicalAddress(int(data[0])<<8 | int(data[1])),
+		}, nil
+
+	case OpSetMenuLanguage:
+		if len(data) != 3 {
+			return nil, IncorrectPacketDataLength{3, len(data)}
+		}
+		// Some TVs send upper case codes.
+		lang := strings.ToLower(string(data))
+		if !isValidLanguage(lang) {
+			return nil, InvalidLanguage{string(data)}
+		}
+		return SetMenuLanguage{
+			Language: lang,
+		}, nil
+
+	case OpGetMenuLanguage:
+		return GetMenuLanguage{}, nil
+
+	case OpInactiveSource:
+		if len(data) != 2 {
+			return nil, IncorrectPacketDataLength{2, len(data)}
+		}
+		return InactiveSource{
+			Addr: PhysicalAddress(int(data[0])<<8 | int(data[1])),
+		}, nil
+
+	case OpRequestActiveSource:
+		return RequestActiveSource{}, nil
+
+	case OpRoutingChange:
+		if len(data) != 4 {
+			return nil, IncorrectPacketDataLength{4, len(data)}
+		}
+		return RoutingChange{
+			From: PhysicalAddress(int(data[0])<<8 | int(data[1])),
+			To:   PhysicalAddress(int(data[2])<<8 | int(data[3])),
+		}, nil
+
+	case OpRoutingInformation:
+		if len(data) != 2 {
+			return nil, IncorrectPacketDataLength{2, len(data)}
+		}
+		return RoutingInformation{
+			Addr: PhysicalAddress(int(data[0])<<8 | int(data[1])),
+		}, nil
+
+	case OpSetStreamPath:
+		if len(data) != 2 {
+			return nil, IncorrectPacketDataLength{2, len(data)}
+		}
+		return SetStreamPath{
+			Addr: PhysicalAddress(int(data[0])<<8 | int(data[1])),
+		}, nil
+
+	case OpImageViewOn:
+		return ImageViewOn{}, nil
+
+	case OpTextViewOn:
+		return TextViewOn{}, nil
 
 	case OpFeatureAbort:
 		if len(data) != 2 {
@@ -281,6 +528,14 @@ func unmarshalCommand(op OpCode, data []byte) (Command, error) {
 			On: data[0] != 0,
 		}, nil
 
+	case OpSystemAudioModeStatus:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return SystemAudioModeStatus{
+			On: data[0] != 0,
+		}, nil
+
 	case OpGiveOSDName:
 		return GiveOSDName{}, nil
 
@@ -340,11 +595,125 @@ func unmarshalCommand(op OpCode, data []byte) (Command, error) {
 		}, nil
 
 	case OpUserControlReleased:
+		// Some devices send the released control as an operand, it's ignored.
+		return UserControlReleased{}, nil
+
+	case OpGiveDeckStatus:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return GiveDeckStatus{
+			Request: StatusRequest(data[0]),
+		}, nil
+
+	case OpDeckStatus:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return DeckStatus{
+			Info: DeckInfo(data[0]),
+		}, nil
+
+	case OpDeckControl:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return DeckControl{
+			Mode: DeckControlMode(data[0]),
+		}, nil
+
+	case OpPlay:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return Play{
+			Mode: PlayMode(data[0]),
+		}, nil
+
+	case OpMenuRequest:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return MenuRequest{
+			Type: MenuRequestType(data[0]),
+		}, nil
+
+	case OpMenuStatus:
 		if len(data) != 1 {
 			return nil, IncorrectPacketDataLength{1, len(data)}
 		}
-		return UserControlReleased{
-			Released: UserControl(data[0]),
+		return MenuStatus{
+			State: MenuState(data[0]),
+		}, nil
+
+	case OpSetOSDString:
+		if len(data) < 2 {
+			return nil, IncorrectPacketDataLength{2, len(data)}
+		}
+		s := string(data[1:])
+		if !isValidOsdString(s) {
+			return nil, InvalidOSDString{}
+		}
+		return SetOSDString{
+			Control: DisplayControl(data[0]),
+			Text:    s,
+		}, nil
+
+	case OpSelectAnalogService:
+		if len(data) != 4 {
+			return nil, IncorrectPacketDataLength{4, len(data)}
+		}
+		return SelectAnalogService{
+			Service: unmarshalAnalogService(data),
+		}, nil
+
+	case OpSelectDigitalService:
+		if len(data) != 7 {
+			return nil, IncorrectPacketDataLength{7, len(data)}
+		}
+		s, err := unmarshalDigitalService(data)
+		if err != nil {
+			return nil, err
+		}
+		return SelectDigitalService{
+			Service: s,
+		}, nil
+
+	case OpTunerStepIncrement:
+		return TunerStepIncrement{}, nil
+
+	case OpTunerStepDecrement:
+		return TunerStepDecrement{}, nil
+
+	case OpGiveTunerDeviceStatus:
+		if len(data) != 1 {
+			return nil, IncorrectPacketDataLength{1, len(data)}
+		}
+		return GiveTunerDeviceStatus{
+			Request: StatusRequest(data[0]),
+		}, nil
+
+	case OpTunerDeviceStatus:
+		// The tuner device info is followed by an analog or a digital service.
+		if len(data) != 5 && len(data) != 8 {
+			return nil, IncorrectPacketDataLength{8, len(data)}
+		}
+		status := TunerStatus{
+			Recording: data[0]&0x80 != 0,
+			Display:   TunerDisplayInfo(data[0] & 0x7f),
+			IsDigital: len(data) == 8,
+		}
+		if status.IsDigital {
+			s, err := unmarshalDigitalService(data[1:])
+			if err != nil {
+				return nil, err
+			}
+			status.Digital = s
+		} else {
+			status.Analog = unmarshalAnalogService(data[1:])
+		}
+		return TunerDeviceStatus{
+			Status: status,
 		}, nil
 
 	case OpVendorCommandWithID:
@@ -361,6 +730,40 @@ func unmarshalCommand(op OpCode, data []byte) (Command, error) {
 	}
 }
 
+// Unmarshals the 4 byte analog service operands.
+func unmarshalAnalogService(data []byte) AnalogService {
+	return AnalogService{
+		BroadcastType: AnalogBroadcastType(data[0]),
+		Frequency:     uint16(data[1])<<8 | uint16(data[2]),
+		System:        BroadcastSystem(data[3]),
+	}
+}
+
+// Unmarshals the 7 byte digital service identification.
+func unmarshalDigitalService(data []byte) (DigitalService, error) {
+	s := DigitalService{
+		System:    DigitalBroadcastSystem(data[0] & 0x7f),
+		ByChannel: data[0]&0x80 != 0,
+	}
+	if !s.ByChannel {
+		s.TransportStreamID = uint16(data[1])<<8 | uint16(data[2])
+		s.ServiceID = uint16(data[3])<<8 | uint16(data[4])
+		s.OriginalNetworkID = uint16(data[5])<<8 | uint16(data[6])
+		return s, nil
+	}
+	// The channel number format takes the upper 6 bits, the major channel number the lower 10.
+	format := data[1] >> 2
+	s.Channel = Channel{
+		TwoPart: format == 0x02,
+		Major:   uint16(data[1]&0x03)<<8 | uint16(data[2]),
+		Minor:   uint16(data[3])<<8 | uint16(data[4]),
+	}
+	if format != 0x01 && format != 0x02 {
+		return DigitalService{}, InvalidChannel{s.Channel}
+	}
+	return s, nil
+}
+
 func MakeUnknownCmd(op OpCode, data []byte) UnkownCmd {
 	return UnkownCmd{
 		op:   op,
@@ -370,12 +773,22 @@ func MakeUnknownCmd(op OpCode, data []byte) UnkownCmd {
 
 func (c UnkownCmd) Op() OpCode                 { return c.op }
 func (c ActiveSource) Op() OpCode              { return OpActiveSource }
+func (c GetMenuLanguage) Op() OpCode           { return OpGetMenuLanguage }
+func (c SetMenuLanguage) Op() OpCode           { return OpSetMenuLanguage }
+func (c InactiveSource) Op() OpCode            { return OpInactiveSource }
+func (c RequestActiveSource) Op() OpCode       { return OpRequestActiveSource }
+func (c RoutingChange) Op() OpCode             { return OpRoutingChange }
+func (c RoutingInformation) Op() OpCode        { return OpRoutingInformation }
+func (c SetStreamPath) Op() OpCode             { return OpSetStreamPath }
+func (c ImageViewOn) Op() OpCode               { return OpImageViewOn }
+func (c TextViewOn) Op() OpCode                { return OpTextViewOn }
 func (c FeatureAbort) Op() OpCode              { return OpFeatureAbort }
 func (c ReportPhysicalAddress) Op() OpCode     { return OpReportPhysicalAddress }
 func (c ReportAudioStatus) Op() OpCode         { return OpReportAudioStatus }
 func (c ReportPowerStatus) Op() OpCode         { return OpReportPowerStatus }
 func (c SetOSDName) Op() OpCode                { return OpSetOSDName }
 func (c SetSystemAudioMode) Op() OpCode        { return OpSetSystemAudioMode }
+func (c SystemAudioModeStatus) Op() OpCode     { return OpSystemAudioModeStatus }
 func (c GiveOSDName) Op() OpCode               { return OpGiveOSDName }
 func (c GiveDevicePowerStatus) Op() OpCode     { return OpGiveDevicePowerStatus }
 func (c GiveDeviceVendorID) Op() OpCode        { return OpGiveDeviceVendorID }
@@ -386,6 +799,19 @@ func (c GetCECVersion) Op() OpCode             { return OpGetCECVersion }
 func (c SystemAudioModeRequest) Op() OpCode    { return OpSystemAudioModeRequest }
 func (c DeviceVendorID) Op() OpCode            { return OpDeviceVendorID }
 func (c CECVersion) Op() OpCode                { return OpCECVersion }
+func (c GiveDeckStatus) Op() OpCode            { return OpGiveDeckStatus }
+func (c DeckStatus) Op() OpCode                { return OpDeckStatus }
+func (c DeckControl) Op() OpCode               { return OpDeckControl }
+func (c Play) Op() OpCode                      { return OpPlay }
+func (c MenuRequest) Op() OpCode               { return OpMenuRequest }
+func (c MenuStatus) Op() OpCode                { return OpMenuStatus }
+func (c SetOSDString) Op() OpCode              { return OpSetOSDString }
+func (c SelectAnalogService) Op() OpCode       { return OpSelectAnalogService }
+func (c SelectDigitalService) Op() OpCode      { return OpSelectDigitalService }
+func (c TunerStepIncrement) Op() OpCode        { return OpTunerStepIncrement }
+func (c TunerStepDecrement) Op() OpCode        { return OpTunerStepDecrement }
+func (c GiveTunerDeviceStatus) Op() OpCode     { return OpGiveTunerDeviceStatus }
+func (c TunerDeviceStatus) Op() OpCode         { return OpTunerDeviceStatus }
 func (c VendorCommandWithID) Op() OpCode       { return OpVendorCommandWithID }
 func (c Standby) Op() OpCode                   { return OpStandby }
 func (c UserControlPressed) Op() OpCode        { return OpUserControlPressed }
@@ -399,6 +825,33 @@ func (c FeatureAbort) Marshal() ([]byte, error) {
 	return []byte{byte(c.Abort), byte(c.Reason)}, nil
 }
 
+func (c ActiveSource) Marshal() ([]byte, error) {
+	return c.Addr.Bytes(), nil
+}
+
+func (c SetMenuLanguage) Marshal() ([]byte, error) {
+	if !isValidLanguage(c.Language) {
+		return nil, InvalidLanguage{c.Language}
+	}
+	return []byte(c.Language), nil
+}
+
+func (c InactiveSource) Marshal() ([]byte, error) {
+	return c.Addr.Bytes(), nil
+}
+
+func (c RoutingChange) Marshal() ([]byte, error) {
+	return append(c.From.Bytes(), c.To.Bytes()...), nil
+}
+
+func (c RoutingInformation) Marshal() ([]byte, error) {
+	return c.Addr.Bytes(), nil
+}
+
+func (c SetStreamPath) Marshal() ([]byte, error) {
+	return c.Addr.Bytes(), nil
+}
+
 func (c ReportPhysicalAddress) Marshal() ([]byte, error) {
 	return append(c.Addr.Bytes(), byte(c.Type)), nil
 }
@@ -438,6 +891,13 @@ func (c SetSystemAudioMode) Marshal() ([]byte, error) {
 	}
 }
 
+func (c SystemAudioModeStatus) Marshal() ([]byte, error) {
+	if c.On {
+		return []byte{0x01}, nil
+	}
+	return []byte{0x00}, nil
+}
+
 func (c DeviceVendorID) Marshal() ([]byte, error) {
 	if !isValidVendorId(c.VendorID) {
 		return nil, InvalidVendorId{}
@@ -464,6 +924,99 @@ func (c UserControlPressed) Marshal() ([]byte, error) {
 	return []byte{byte(c.Pressed)}, nil
 }
 
-func (c UserControlReleased) Marshal() ([]byte, error) {
-	return []byte{byte(c.Released)}, nil
+func (c GiveDeckStatus) Marshal() ([]byte, error) {
+	return []byte{byte(c.Request)}, nil
+}
+
+func (c DeckStatus) Marshal() ([]byte, error) {
+	return []byte{byte(c.Info)}, nil
+}
+
+func (c DeckControl) Marshal() ([]byte, error) {
+	return []byte{byte(c.Mode)}, nil
+}
+
+func (c Play) Marshal() ([]byte, error) {
+	return []byte{byte(c.Mode)}, nil
+}
+
+func (c MenuRequest) Marshal() ([]byte, error) {
+	return []byte{byte(c.Type)}, nil
+}
+
+func (c MenuStatus) Marshal() ([]byte, error) {
+	return []byte{byte(c.State)}, nil
+}
+
+func (c SetOSDString) Marshal() ([]byte, error) {
+	if !isValidOsdString(c.Text) {
+		return nil, InvalidOSDString{}
+	}
+	return append([]byte{byte(c.Control)}, c.Text...), nil
+}
+
+func (c SelectAnalogService) Marshal() ([]byte, error) {
+	return marshalAnalogService(c.Service), nil
+}
+
+func (c SelectDigitalService) Marshal() ([]byte, error) {
+	return marshalDigitalService(c.Service)
+}
+
+func (c GiveTunerDeviceStatus) Marshal() ([]byte, error) {
+	return []byte{byte(c.Request)}, nil
+}
+
+func (c TunerDeviceStatus) Marshal() ([]byte, error) {
+	info := byte(c.Status.Display) & 0x7f
+	if c.Status.Recording {
+		info |= 0x80
+	}
+	if !c.Status.IsDigital {
+		return append([]byte{info}, marshalAnalogService(c.Status.Analog)...), nil
+	}
+	data, err := marshalDigitalService(c.Status.Digital)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{info}, data...), nil
+}
+
+func marshalAnalogService(s AnalogService) []byte {
+	return []byte{
+		byte(s.BroadcastType),
+		byte(s.Frequency >> 8),
+		byte(s.Frequency),
+		byte(s.System),
+	}
+}
+
+func marshalDigitalService(s DigitalService) ([]byte, error) {
+	if !s.ByChannel {
+		return []byte{
+			byte(s.System) & 0x7f,
+			byte(s.TransportStreamID >> 8),
+			byte(s.TransportStreamID),
+			byte(s.ServiceID >> 8),
+			byte(s.ServiceID),
+			byte(s.OriginalNetworkID >> 8),
+			byte(s.OriginalNetworkID),
+		}, nil
+	}
+	if s.Channel.Major > 999 {
+		return nil, InvalidChannel{s.Channel}
+	}
+	format := byte(0x01)
+	if s.Channel.TwoPart {
+		format = 0x02
+	}
+	return []byte{
+		byte(s.System)&0x7f | 0x80,
+		format<<2 | byte(s.Channel.Major>>8),
+		byte(s.Channel.Major),
+		byte(s.Channel.Minor >> 8),
+		byte(s.Channel.Minor),
+		0x00,
+		0x00,
+	}, nil
 }
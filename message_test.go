@@ -44,6 +44,8 @@ var cmdTests = []struct {
 	{"set_osd_name", SetOSDName{"osd name"}, OpSetOSDName, []byte("osd name")},
 	{"set_system_audio_mode_false", SetSystemAudioMode{false}, OpSetSystemAudioMode, []byte{0x00}},
 	{"set_system_audio_mode_true", SetSystemAudioMode{true}, OpSetSystemAudioMode, []byte{0x01}},
+	{"system_audio_mode_status_false", SystemAudioModeStatus{false}, OpSystemAudioModeStatus, []byte{0x00}},
+	{"system_audio_mode_status_true", SystemAudioModeStatus{true}, OpSystemAudioModeStatus, []byte{0x01}},
 	{"give_audio_status", GiveAudioStatus{}, OpGiveAudioStatus, []byte{}},
 	{"give_system_audio_mode_status", GiveSystemAudioModeStatus{}, OpGiveSystemAudioModeStatus, []byte{}},
 	{"give_osd_name", GiveOSDName{}, OpGiveOSDName, []byte{}},
@@ -56,9 +58,35 @@ var cmdTests = []struct {
 	{"device_vendor_id", DeviceVendorID{0xabcd}, OpDeviceVendorID, []byte{0x00, 0xab, 0xcd}},
 	{"cec_version", CECVersion{cecVersion}, OpCECVersion, []byte{cecVersion}},
 	{"user_control_pressed", UserControlPressed{UcBackward}, OpUserControlPressed, []byte{0x4c}},
-	{"user_control_released", UserControlReleased{UcBackward}, OpUserControlReleased, []byte{0x4c}},
+	{"user_control_released", UserControlReleased{}, OpUserControlReleased, []byte{}},
 	{"standby", Standby{}, OpStandby, []byte{}},
-	{"active_source", ActiveSource{}, OpActiveSource, []byte{}},
+	{"active_source", ActiveSource{PhysicalAddress(0xabcd)}, OpActiveSource, []byte{0xab, 0xcd}},
+	{"set_osd_string", SetOSDString{DisplayUntilCleared, "Recording"}, OpSetOSDString, append([]byte{0x40}, "Recording"...)},
+	{"get_menu_language", GetMenuLanguage{}, OpGetMenuLanguage, []byte{}},
+	{"set_menu_language", SetMenuLanguage{"eng"}, OpSetMenuLanguage, []byte("eng")},
+	{"inactive_source", InactiveSource{PhysicalAddress(0xabcd)}, OpInactiveSource, []byte{0xab, 0xcd}},
+	{"request_active_source", RequestActiveSource{}, OpRequestActiveSource, []byte{}},
+	{"routing_change", RoutingChange{PhysicalAddress(0x1000), PhysicalAddress(0x2000)}, OpRoutingChange, []byte{0x10, 0x00, 0x20, 0x00}},
+	{"routing_information", RoutingInformation{PhysicalAddress(0x2100)}, OpRoutingInformation, []byte{0x21, 0x00}},
+	{"set_stream_path", SetStreamPath{PhysicalAddress(0x2100)}, OpSetStreamPath, []byte{0x21, 0x00}},
+	{"image_view_on", ImageViewOn{}, OpImageViewOn, []byte{}},
+	{"text_view_on", TextViewOn{}, OpTextViewOn, []byte{}},
+	{"give_deck_status", GiveDeckStatus{StatusRequestOnce}, OpGiveDeckStatus, []byte{0x03}},
+	{"deck_status", DeckStatus{DeckInfoStill}, OpDeckStatus, []byte{0x14}},
+	{"deck_control", DeckControl{DeckControlStop}, OpDeckControl, []byte{0x03}},
+	{"play", Play{PlayForward}, OpPlay, []byte{0x24}},
+	{"menu_request", MenuRequest{MenuRequestQuery}, OpMenuRequest, []byte{0x02}},
+	{"menu_status", MenuStatus{MenuDeactivated}, OpMenuStatus, []byte{0x01}},
+	{"select_analog_service", SelectAnalogService{AnalogService{AnalogTerrestrial, 0x1234, BroadcastPALBG}}, OpSelectAnalogService, []byte{0x02, 0x12, 0x34, 0x00}},
+	{"select_digital_service_dvb", SelectDigitalService{DigitalService{System: DigitalDVBT, TransportStreamID: 0x0401, ServiceID: 0x0102, OriginalNetworkID: 0x2114}}, OpSelectDigitalService, []byte{0x1b, 0x04, 0x01, 0x01, 0x02, 0x21, 0x14}},
+	{"select_digital_service_atsc", SelectDigitalService{DigitalService{System: DigitalATSCCable, TransportStreamID: 0x0010, ServiceID: 0x0003}}, OpSelectDigitalService, []byte{0x10, 0x00, 0x10, 0x00, 0x03, 0x00, 0x00}},
+	{"select_digital_service_one_part_channel", SelectDigitalService{DigitalService{System: DigitalARIBT, ByChannel: true, Channel: Channel{Minor: 0x0123}}}, OpSelectDigitalService, []byte{0x8a, 0x04, 0x00, 0x01, 0x23, 0x00, 0x00}},
+	{"select_digital_service_two_part_channel", SelectDigitalService{DigitalService{System: DigitalATSCTerrestrial, ByChannel: true, Channel: Channel{true, 999, 2}}}, OpSelectDigitalService, []byte{0x92, 0x0b, 0xe7, 0x00, 0x02, 0x00, 0x00}},
+	{"tuner_step_increment", TunerStepIncrement{}, OpTunerStepIncrement, []byte{}},
+	{"tuner_step_decrement", TunerStepDecrement{}, OpTunerStepDecrement, []byte{}},
+	{"give_tuner_device_status", GiveTunerDeviceStatus{StatusRequestOn}, OpGiveTunerDeviceStatus, []byte{0x01}},
+	{"tuner_device_status_analog", TunerDeviceStatus{TunerStatus{Recording: true, Display: TunerDisplayAnalog, Analog: AnalogService{AnalogCable, 0x0100, BroadcastNTSCM}}}, OpTunerDeviceStatus, []byte{0x82, 0x00, 0x01, 0x00, 0x03}},
+	{"tuner_device_status_digital", TunerDeviceStatus{TunerStatus{Display: TunerDisplayNone, IsDigital: true, Digital: DigitalService{System: DigitalDVBS2, ServiceID: 0x0007}}}, OpTunerDeviceStatus, []byte{0x01, 0x1a, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00}},
 	{"vendor_command_with_id", VendorCommandWithID{}, OpVendorCommandWithID, []byte{}},
 }
 
@@ -119,6 +147,12 @@ func TestCommand_Marshal_Fail(t *testing.T) {
 		{"empty_osd_name", SetOSDName{""}, InvalidOSDName{}},
 		{"osd_name_too_long", SetOSDName{"toolongtooolong"}, InvalidOSDName{}},
 		{"device_id_too_large", DeviceVendorID{0xabcdef00}, InvalidVendorId{}},
+		{"osd_string_empty", SetOSDString{DisplayDefaultTime, ""}, InvalidOSDString{}},
+		{"osd_string_too_long", SetOSDString{DisplayDefaultTime, "toolongtoolong"}, InvalidOSDString{}},
+		{"channel_major_too_large", SelectDigitalService{DigitalService{ByChannel: true, Channel: Channel{true, 1000, 0}}}, InvalidChannel{}},
+		{"tuner_status_channel_major_too_large", TunerDeviceStatus{TunerStatus{IsDigital: true, Digital: DigitalService{ByChannel: true, Channel: Channel{true, 1000, 0}}}}, InvalidChannel{}},
+		{"menu_language_too_long", SetMenuLanguage{"engl"}, InvalidLanguage{}},
+		{"menu_language_invalid", SetMenuLanguage{"EN1"}, InvalidLanguage{}},
 	}
 
 	for _, test := range tests {
@@ -152,11 +186,32 @@ func TestUnmarshalMessage_Fail(t *testing.T) {
 		{"set_osd_name_too_long", OpSetOSDName, []byte("toolongtooolong"), InvalidOSDName{}},
 		{"set_osd_name_too_short", OpSetOSDName, []byte(""), InvalidOSDName{}},
 		{"set_osd_name_utf8", OpSetOSDName, []byte("fäil"), InvalidOSDName{}},
+		{"system_audio_mode_status_no_payload", OpSystemAudioModeStatus, []byte{}, IncorrectPacketDataLength{}},
 		{"system_audio_mode_request_invalid_payload", OpSystemAudioModeRequest, []byte{0x00}, IncorrectPacketDataLength{}},
 		{"device_vendor_id_payload_too_short", OpDeviceVendorID, []byte{0x00}, IncorrectPacketDataLength{}},
 		{"cec_version_no_payload", OpCECVersion, []byte{}, IncorrectPacketDataLength{}},
 		{"user_control_pressed_no_payload", OpUserControlPressed, []byte{}, IncorrectPacketDataLength{}},
-		{"user_control_released_no_payload", OpUserControlReleased, []byte{}, IncorrectPacketDataLength{}},
+		{"active_source_no_payload", OpActiveSource, []byte{}, IncorrectPacketDataLength{}},
+		{"active_source_payload_too_long", OpActiveSource, []byte{0x00, 0x00, 0x00}, IncorrectPacketDataLength{}},
+		{"set_menu_language_too_short", OpSetMenuLanguage, []byte("en"), IncorrectPacketDataLength{}},
+		{"set_menu_language_not_letters", OpSetMenuLanguage, []byte{'e', 0x00, 'g'}, InvalidLanguage{}},
+		{"inactive_source_no_payload", OpInactiveSource, []byte{}, IncorrectPacketDataLength{}},
+		{"routing_change_payload_too_short", OpRoutingChange, []byte{0x10, 0x00}, IncorrectPacketDataLength{}},
+		{"routing_information_no_payload", OpRoutingInformation, []byte{}, IncorrectPacketDataLength{}},
+		{"set_stream_path_no_payload", OpSetStreamPath, []byte{}, IncorrectPacketDataLength{}},
+		{"give_deck_status_no_payload", OpGiveDeckStatus, []byte{}, IncorrectPacketDataLength{}},
+		{"deck_status_no_payload", OpDeckStatus, []byte{}, IncorrectPacketDataLength{}},
+		{"deck_control_no_payload", OpDeckControl, []byte{}, IncorrectPacketDataLength{}},
+		{"play_no_payload", OpPlay, []byte{}, IncorrectPacketDataLength{}},
+		{"menu_request_no_payload", OpMenuRequest, []byte{}, IncorrectPacketDataLength{}},
+		{"menu_status_no_payload", OpMenuStatus, []byte{}, IncorrectPacketDataLength{}},
+		{"select_analog_service_too_short", OpSelectAnalogService, []byte{0x00, 0x12, 0x34}, IncorrectPacketDataLength{}},
+		{"select_digital_service_too_short", OpSelectDigitalService, []byte{0x1b, 0x00}, IncorrectPacketDataLength{}},
+		{"select_digital_service_invalid_channel_format", OpSelectDigitalService, []byte{0x9b, 0x0c, 0x00, 0x00, 0x01, 0x00, 0x00}, InvalidChannel{}},
+		{"give_tuner_device_status_no_payload", OpGiveTunerDeviceStatus, []byte{}, IncorrectPacketDataLength{}},
+		{"tuner_device_status_invalid_length", OpTunerDeviceStatus, []byte{0x00, 0x00, 0x00}, IncorrectPacketDataLength{}},
+		{"set_osd_string_no_text", OpSetOSDString, []byte{0x00}, IncorrectPacketDataLength{}},
+		{"set_osd_string_utf8", OpSetOSDString, append([]byte{0x00}, "fäil"...), InvalidOSDString{}},
 	}
 
 	for _, test := range tests {
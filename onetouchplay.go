@@ -0,0 +1,36 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+// Performs One Touch Play: Turns on the TV with ImageViewOn and announces this device as the
+// active source. The TV then switches to this device's input.
+func (x *Cec) OneTouchPlay() error {
+	return x.oneTouchPlay(ImageViewOn{})
+}
+
+// Same as OneTouchPlay, but uses TextViewOn to turn on the TV, which also removes any menus shown
+// on the TV.
+func (x *Cec) OneTouchPlayText() error {
+	return x.oneTouchPlay(TextViewOn{})
+}
+
+func (x *Cec) oneTouchPlay(viewOn Command) error {
+	if err := x.Send(TV, viewOn); err != nil {
+		return err
+	}
+	return x.Send(Broadcast, ActiveSource{
+		Addr: x.dev.GetPhysicalAddress(),
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestOneTouchPlay(t *testing.T) {
+	activeSource := Packet{Playback1, Broadcast, OpActiveSource, fake.PhysicalAddress.Bytes()}
+	tests := []struct {
+		name     string
+		play     func(c *Cec) error
+		txStatus TxStatus
+		err      error
+		out      []Packet
+	}{
+		{
+			name: "image_view_on",
+			play: (*Cec).OneTouchPlay,
+			out:  []Packet{{Playback1, TV, OpImageViewOn, []byte{}}, activeSource},
+		}, {
+			name: "text_view_on",
+			play: (*Cec).OneTouchPlayText,
+			out:  []Packet{{Playback1, TV, OpTextViewOn, []byte{}}, activeSource},
+		}, {
+			// Without a TV, there is no point in announcing the active source.
+			name:     "no_tv",
+			play:     (*Cec).OneTouchPlay,
+			txStatus: TxNack,
+			err:      TransmitError{Status: TxNack},
+			out:      []Packet{{Playback1, TV, OpImageViewOn, []byte{}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := fake.New(Playback1, DeviceTypePlayback)
+			d.SetTxStatus(TV, test.txStatus)
+			c, err := New(d, Config{OSDName: "test"})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			actual := d.Run(nil, func() {
+				if err := test.play(c); err != test.err {
+					t.Errorf("Expected %v, got %v", test.err, err)
+				}
+			})
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+// Displays a short text message on the TV. The text must be between 1 and 13 ASCII characters. Not
+// all TVs support this, they abort the message or ignore it.
+func (x *Cec) ShowOnTV(text string, mode DisplayControl) error {
+	return x.Send(TV, SetOSDString{Control: mode, Text: text})
+}
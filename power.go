@@ -0,0 +1,95 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import "sync"
+
+// The PowerHandler implements System Standby. It tracks the power status of this device, answers
+// GiveDevicePowerStatus, and passes Standby requests on to a callback.
+type PowerHandler struct {
+	onStandby func(x *Cec)
+
+	mtx    sync.Mutex
+	status PowerStatus
+}
+
+// Creates a new PowerHandler with the initial power status.
+//
+// When another device requests standby, the power status changes to PowerStatusStandbyTransition
+// and onStandby is called if it isn't nil. It should initiate the standby and call SetPowerStatus
+// once the device is in standby. onStandby is called from Run and must not block. Standby requests
+// are ignored while the device is in standby or transitioning to it.
+func NewPowerHandler(status PowerStatus, onStandby func(x *Cec)) *PowerHandler {
+	return &PowerHandler{
+		onStandby: onStandby,
+		status:    status,
+	}
+}
+
+// Returns the power status of this device.
+func (h *PowerHandler) PowerStatus() PowerStatus {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.status
+}
+
+// Sets the power status of this device, e.g. once the device finished a transition or because it
+// was turned on by other means than CEC.
+func (h *PowerHandler) SetPowerStatus(s PowerStatus) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.status = s
+}
+
+// Moves to PowerStatusStandbyTransition. Returns false if the device is already in standby or
+// transitioning to it.
+func (h *PowerHandler) beginStandby() bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.status == PowerStatusStandby || h.status == PowerStatusStandbyTransition {
+		return false
+	}
+	h.status = PowerStatusStandbyTransition
+	return true
+}
+
+// PowerHandler implements Handler.
+func (h *PowerHandler) HandleMessage(x *Cec, msg Message) bool {
+	switch msg.Cmd.(type) {
+	case GiveDevicePowerStatus:
+		x.Reply(msg.Initiator, ReportPowerStatus{Power: h.PowerStatus()})
+		return true
+
+	case Standby:
+		if h.beginStandby() {
+			x.log.Info("Standby requested", messageAttrs(msg)...)
+			if h.onStandby != nil {
+				h.onStandby(x)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Requests all devices on the bus to go to standby.
+func (x *Cec) StandbyAll() error {
+	return x.Send(Broadcast, Standby{})
+}
+
+// Requests the TV to go to standby.
+func (x *Cec) StandbyTV() error {
+	return x.Send(TV, Standby{})
+}
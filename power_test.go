@@ -0,0 +1,158 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestPowerHandler(t *testing.T) {
+	giveStatus := Packet{TV, Playback1, OpGiveDevicePowerStatus, []byte{}}
+	tests := []struct {
+		name     string
+		status   PowerStatus
+		complete bool // Whether the standby callback completes the transition.
+		in       []Packet
+		out      []Packet
+		standbys int
+		want     PowerStatus
+	}{
+		{
+			name:   "give_device_power_status",
+			status: PowerStatusOn,
+			in:     []Packet{giveStatus},
+			out: []Packet{
+				{Playback1, TV, OpReportPowerStatus, []byte{byte(PowerStatusOn)}},
+			},
+			want: PowerStatusOn,
+		}, {
+			name:     "standby_broadcast",
+			status:   PowerStatusOn,
+			complete: true,
+			in: []Packet{
+				{TV, Broadcast, OpStandby, []byte{}},
+				giveStatus,
+			},
+			out: []Packet{
+				{Playback1, TV, OpReportPowerStatus, []byte{byte(PowerStatusStandby)}},
+			},
+			standbys: 1,
+			want:     PowerStatusStandby,
+		}, {
+			name:   "standby_direct_in_transition",
+			status: PowerStatusOn,
+			in: []Packet{
+				{TV, Playback1, OpStandby, []byte{}},
+				giveStatus,
+				// Already transitioning, the request is ignored.
+				{TV, Playback1, OpStandby, []byte{}},
+			},
+			out: []Packet{
+				{Playback1, TV, OpReportPowerStatus, []byte{byte(PowerStatusStandbyTransition)}},
+			},
+			standbys: 1,
+			want:     PowerStatusStandbyTransition,
+		}, {
+			name:   "standby_in_standby",
+			status: PowerStatusStandby,
+			in: []Packet{
+				{TV, Broadcast, OpStandby, []byte{}},
+			},
+			out:  []Packet{},
+			want: PowerStatusStandby,
+		}, {
+			name:     "standby_while_turning_on",
+			status:   PowerStatusOnTransition,
+			complete: true,
+			in: []Packet{
+				{Unregistered, Broadcast, OpStandby, []byte{}},
+			},
+			out:      []Packet{},
+			standbys: 1,
+			want:     PowerStatusStandby,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			standbys := 0
+			var h *PowerHandler
+			h = NewPowerHandler(test.status, func(x *Cec) {
+				standbys++
+				if test.complete {
+					h.SetPowerStatus(PowerStatusStandby)
+				}
+			})
+			d := fake.New(Playback1, DeviceTypePlayback)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(h)
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+			if standbys != test.standbys {
+				t.Errorf("Expected %d standby callbacks, got %d", test.standbys, standbys)
+			}
+			if s := h.PowerStatus(); s != test.want {
+				t.Errorf("Expected power status %s, got %s", test.want, s)
+			}
+		})
+	}
+}
+
+func TestStandby(t *testing.T) {
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	actual := d.Run(nil, func() {
+		if err := c.StandbyTV(); err != nil {
+			t.Errorf("StandbyTV failed: %s", err)
+		}
+		if err := c.StandbyAll(); err != nil {
+			t.Errorf("StandbyAll failed: %s", err)
+		}
+	})
+	expected := []Packet{
+		{Playback1, TV, OpStandby, []byte{}},
+		{Playback1, Broadcast, OpStandby, []byte{}},
+	}
+	if diff := cmp.Diff(actual, expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, actual, diff)
+	}
+}
+
+func TestPowerHandler_NilCallback(t *testing.T) {
+	h := NewPowerHandler(PowerStatusOn, nil)
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	c.AddHandler(h)
+	d.Run([]Packet{{TV, Broadcast, OpStandby, []byte{}}}, func() { c.Run() })
+	if s := h.PowerStatus(); s != PowerStatusStandbyTransition {
+		t.Errorf("Expected power status %s, got %s", PowerStatusStandbyTransition, s)
+	}
+}
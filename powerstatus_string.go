@@ -4,13 +4,22 @@ package cec
 
 import "strconv"
 
-const _PowerStatus_name = "PowerStatusOnPowerStatusStandbyPowerStatusOnTransitionPowerStatusStandbyTransition"
+const (
+	_PowerStatus_name_0 = "PowerStatusOnPowerStatusStandbyPowerStatusOnTransitionPowerStatusStandbyTransition"
+	_PowerStatus_name_1 = "PowerStatusUnknown"
+)
 
-var _PowerStatus_index = [...]uint8{0, 13, 31, 54, 82}
+var (
+	_PowerStatus_index_0 = [...]uint8{0, 13, 31, 54, 82}
+)
 
 func (i PowerStatus) String() string {
-	if i >= PowerStatus(len(_PowerStatus_index)-1) {
+	switch {
+	case 0 <= i && i <= 3:
+		return _PowerStatus_name_0[_PowerStatus_index_0[i]:_PowerStatus_index_0[i+1]]
+	case i == 255:
+		return _PowerStatus_name_1
+	default:
 		return "PowerStatus(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _PowerStatus_name[_PowerStatus_index[i]:_PowerStatus_index[i+1]]
 }
@@ -0,0 +1,130 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"sync"
+	"time"
+)
+
+// Used for DeviceState.VendorID if the vendor ID is unknown.
+const VendorIDUnknown uint32 = 0xffffffff
+
+// DeviceState is the last known state of a remote device as observed on the bus.
+type DeviceState struct {
+	LogicalAddr     LogicalAddr
+	PhysicalAddress PhysicalAddress // InvalidAddress if unknown.
+	DeviceType      DeviceType      // DeviceTypeInvalid if unknown.
+	OSDName         string          // Empty if unknown.
+	VendorID        uint32          // VendorIDUnknown if unknown.
+	CECVersion      byte            // Zero if unknown.
+	PowerStatus     PowerStatus     // PowerStatusUnknown if unknown.
+	MenuLanguage    string          // Empty if unknown.
+	LastSeen        time.Time       // When the device last sent a message.
+}
+
+// Returns the state of the device at a with all fields set to unknown.
+func unknownDeviceState(a LogicalAddr) DeviceState {
+	return DeviceState{
+		LogicalAddr:     a,
+		PhysicalAddress: InvalidAddress,
+		DeviceType:      DeviceTypeInvalid,
+		VendorID:        VendorIDUnknown,
+		PowerStatus:     PowerStatusUnknown,
+	}
+}
+
+// Caches the state of all remote devices, keyed by logical address.
+type remoteCache struct {
+	mtx     sync.RWMutex
+	devices map[LogicalAddr]*DeviceState
+}
+
+// Updates the cache from an incoming message.
+func (c *remoteCache) observe(msg Message, now time.Time) {
+	if msg.Initiator == Unregistered {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.devices == nil {
+		c.devices = make(map[LogicalAddr]*DeviceState)
+	}
+	s, ok := c.devices[msg.Initiator]
+	if !ok {
+		u := unknownDeviceState(msg.Initiator)
+		s = &u
+		c.devices[msg.Initiator] = s
+	}
+	s.LastSeen = now
+
+	switch cmd := msg.Cmd.(type) {
+	case ReportPhysicalAddress:
+		s.PhysicalAddress = cmd.Addr
+		s.DeviceType = cmd.Type
+	case ActiveSource:
+		s.PhysicalAddress = cmd.Addr
+	case InactiveSource:
+		s.PhysicalAddress = cmd.Addr
+	case SetOSDName:
+		s.OSDName = cmd.Name
+	case DeviceVendorID:
+		s.VendorID = cmd.VendorID
+	case CECVersion:
+		s.CECVersion = cmd.Version
+	case ReportPowerStatus:
+		s.PowerStatus = cmd.Power
+	case SetMenuLanguage:
+		s.MenuLanguage = cmd.Language
+	}
+}
+
+func (c *remoteCache) get(a LogicalAddr) (DeviceState, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	s, ok := c.devices[a]
+	if !ok {
+		return DeviceState{}, false
+	}
+	return *s, true
+}
+
+func (c *remoteCache) all() []DeviceState {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	var states []DeviceState
+	for a := TV; a < Unregistered; a++ {
+		if s, ok := c.devices[a]; ok {
+			states = append(states, *s)
+		}
+	}
+	return states
+}
+
+// Returns the last known state of the device with logical address a. The second return value is
+// false if the device hasn't sent any messages yet.
+//
+// The state is updated from all incoming messages while Run is running, it doesn't generate any
+// bus traffic. Use Scan or Request to query a device actively.
+func (x *Cec) RemoteState(a LogicalAddr) (DeviceState, bool) {
+	return x.remote.get(a)
+}
+
+// Returns the last known state of all devices that sent messages, ordered by logical address.
+// See RemoteState.
+func (x *Cec) RemoteStates() []DeviceState {
+	return x.remote.all()
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+func TestRemoteState(t *testing.T) {
+	d := fake.New(Playback1, DeviceTypePlayback)
+	c, err := New(d, Config{OSDName: "test", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	start := time.Now()
+	d.Run([]Packet{
+		{TV, Broadcast, OpReportPhysicalAddress, []byte{0x00, 0x00, byte(DeviceTypeTV)}},
+		{TV, Playback1, OpSetOSDName, []byte("tv")},
+		{TV, Broadcast, OpDeviceVendorID, []byte{0x00, 0x90, 0x3e}},
+		{TV, Playback1, OpCECVersion, []byte{0x05}},
+		{TV, Playback1, OpReportPowerStatus, []byte{byte(PowerStatusStandby)}},
+		{TV, Broadcast, OpSetMenuLanguage, []byte("deu")},
+		{Playback2, Broadcast, OpActiveSource, []byte{0x21, 0x00}},
+		{Unregistered, Broadcast, OpReportPhysicalAddress, []byte{0x30, 0x00, byte(DeviceTypeSwitch)}},
+	}, func() { c.Run() })
+
+	expected := []DeviceState{
+		{
+			LogicalAddr:     TV,
+			PhysicalAddress: 0x0000,
+			DeviceType:      DeviceTypeTV,
+			OSDName:         "tv",
+			VendorID:        0x00903e,
+			CECVersion:      0x05,
+			PowerStatus:     PowerStatusStandby,
+			MenuLanguage:    "deu",
+		}, {
+			LogicalAddr:     Playback2,
+			PhysicalAddress: 0x2100,
+			DeviceType:      DeviceTypeInvalid,
+			VendorID:        VendorIDUnknown,
+			PowerStatus:     PowerStatusUnknown,
+		},
+	}
+	states := c.RemoteStates()
+	if diff := cmp.Diff(states, expected, cmpopts.IgnoreFields(DeviceState{}, "LastSeen")); diff != "" {
+		t.Errorf("Unexpected remote states: %s", diff)
+	}
+	for _, s := range states {
+		if s.LastSeen.Before(start) {
+			t.Errorf("Expected %s to be seen after %s, got %s", s.LogicalAddr, start, s.LastSeen)
+		}
+	}
+
+	if s, ok := c.RemoteState(TV); !ok || s.PowerStatus != PowerStatusStandby {
+		t.Errorf("Expected TV to be in standby, got %+v (%t)", s, ok)
+	}
+	if _, ok := c.RemoteState(AudioSystem); ok {
+		t.Errorf("Expected no state for a device that didn't send any messages.")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"context"
+)
+
+// A pending request waiting for its response.
+type request struct {
+	follower LogicalAddr
+	op       OpCode
+	response OpCode
+	c        chan Message
+}
+
+// Returns true if msg answers this request.
+func (r *request) matches(msg Message) bool {
+	if r.follower != Broadcast && msg.Initiator != r.follower {
+		return false
+	}
+	if abort, ok := msg.Cmd.(FeatureAbort); ok {
+		return abort.Abort == r.op
+	}
+	return msg.Cmd.Op() == r.response
+}
+
+// Sends cmd to follower and waits until the follower answers with the expected response, the
+// follower aborts the request, or ctx is done. If follower is Broadcast, the response is accepted
+// from any device.
+//
+// The expected response is determined by the opcode of cmd, e.g. a GiveDevicePowerStatus is
+// answered by a ReportPowerStatus. A FeatureAbort is returned as FeatureAbortError. The response is
+// consumed and not passed on to any Handler.
+//
+// Responses are dispatched by Run, Request must therefore not be called from within a Handler. If
+// the Cec is closed while waiting, Request returns Closed.
+func (x *Cec) Request(ctx context.Context, follower LogicalAddr, cmd Command) (Command, error) {
+	resp, ok := getResponseOpCode(cmd.Op())
+	if !ok {
+		return nil, NotARequest{cmd.Op()}
+	}
+	return x.exchange(ctx, follower, cmd.Op(), resp, func() error {
+		return x.Send(follower, cmd)
+	})
+}
+
+// Calls send and waits for a response with the opcode resp from follower or a FeatureAbort of op.
+// See Request.
+func (x *Cec) exchange(ctx context.Context, follower LogicalAddr, op, resp OpCode, send func() error) (Command, error) {
+	r := &request{
+		follower: follower,
+		op:       op,
+		response: resp,
+		c:        make(chan Message, 1),
+	}
+	x.addRequest(r)
+	defer x.removeRequest(r)
+
+	if err := send(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-r.c:
+		if abort, ok := msg.Cmd.(FeatureAbort); ok {
+			return nil, FeatureAbortError{
+				Op:     abort.Abort,
+				Reason: abort.Reason,
+			}
+		}
+		return msg.Cmd, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-x.closing:
+		return nil, Closed{}
+	}
+}
+
+func (x *Cec) addRequest(r *request) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+	x.requests = append(x.requests, r)
+}
+
+func (x *Cec) removeRequest(r *request) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+	for i, s := range x.requests {
+		if s == r {
+			x.requests = append(x.requests[:i], x.requests[i+1:]...)
+			return
+		}
+	}
+}
+
+// Passes msg to the first pending request it answers. Returns true if msg was consumed.
+func (x *Cec) dispatchResponse(msg Message) bool {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+	for i, r := range x.requests {
+		if r.matches(msg) {
+			r.c <- msg
+			// Remove the request immediately to make sure that it doesn't consume another message.
+			x.requests = append(x.requests[:i], x.requests[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
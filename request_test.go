@@ -0,0 +1,163 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+// responder is a device that answers the first sent packet with the packets returned by respond.
+type responder struct {
+	Device
+	in      chan Packet
+	respond func(p Packet) []Packet
+	sent    bool
+}
+
+func newResponder(respond func(p Packet) []Packet) *responder {
+	return &responder{
+		Device:  fake.New(Playback1, DeviceTypePlayback),
+		in:      make(chan Packet, 10),
+		respond: respond,
+	}
+}
+
+func (d *responder) Receive() <-chan Packet {
+	return d.in
+}
+
+func (d *responder) Send(follower LogicalAddr, op OpCode, payload []byte) {
+	if d.sent {
+		return
+	}
+	d.sent = true
+	for _, p := range d.respond(Packet{d.GetLogicalAddress(), follower, op, payload}) {
+		d.in <- p
+	}
+}
+
+func (d *responder) Reply(follower LogicalAddr, op OpCode, payload []byte) {
+	d.Send(follower, op, payload)
+}
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		follower LogicalAddr
+		cmd      Command
+		respond  func(p Packet) []Packet
+		want     Command
+		err      error
+	}{
+		{
+			name:     "response",
+			follower: TV,
+			cmd:      GiveDevicePowerStatus{},
+			respond: func(p Packet) []Packet {
+				return []Packet{{TV, Playback1, OpReportPowerStatus, []byte{byte(PowerStatusStandby)}}}
+			},
+			want: ReportPowerStatus{PowerStatusStandby},
+		},
+		{
+			name:     "broadcast_response",
+			follower: TV,
+			cmd:      GivePhysicalAddress{},
+			respond: func(p Packet) []Packet {
+				return []Packet{{TV, Broadcast, OpReportPhysicalAddress, []byte{0x00, 0x00, byte(DeviceTypeTV)}}}
+			},
+			want: ReportPhysicalAddress{Addr: 0x0000, Type: DeviceTypeTV},
+		},
+		{
+			name:     "response_from_any_device",
+			follower: Broadcast,
+			cmd:      GivePhysicalAddress{},
+			respond: func(p Packet) []Packet {
+				return []Packet{{AudioSystem, Broadcast, OpReportPhysicalAddress, []byte{0x10, 0x00, byte(DeviceTypeAudio)}}}
+			},
+			want: ReportPhysicalAddress{Addr: 0x1000, Type: DeviceTypeAudio},
+		},
+		{
+			name:     "unrelated_messages_are_skipped",
+			follower: TV,
+			cmd:      GiveOSDName{},
+			respond: func(p Packet) []Packet {
+				return []Packet{
+					{AudioSystem, Playback1, OpSetOSDName, []byte("audio")},
+					{TV, Playback1, OpReportPowerStatus, []byte{byte(PowerStatusStandby)}},
+					{TV, Playback1, OpSetOSDName, []byte("tv")},
+				}
+			},
+			want: SetOSDName{"tv"},
+		},
+		{
+			name:     "feature_abort",
+			follower: TV,
+			cmd:      GiveAudioStatus{},
+			respond: func(p Packet) []Packet {
+				return []Packet{{TV, Playback1, OpFeatureAbort, []byte{byte(OpGiveAudioStatus), byte(AbortRefused)}}}
+			},
+			err: FeatureAbortError{Op: OpGiveAudioStatus, Reason: AbortRefused},
+		},
+		{
+			name:     "timeout",
+			follower: TV,
+			cmd:      GiveDevicePowerStatus{},
+			respond:  func(p Packet) []Packet { return nil },
+			err:      context.DeadlineExceeded,
+		},
+		{
+			name:     "not_a_request",
+			follower: TV,
+			cmd:      Standby{},
+			respond:  func(p Packet) []Packet { return nil },
+			err:      NotARequest{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newResponder(test.respond)
+			c, err := New(d, Config{
+				OSDName: "test",
+			})
+			if err != nil {
+				t.Errorf("Error setting up %s", err)
+				return
+			}
+			go c.Run()
+			defer close(d.in)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			got, err := c.Request(ctx, test.follower, test.cmd)
+			if reflect.TypeOf(err) != reflect.TypeOf(test.err) {
+				t.Errorf("Expected error of type %T, got %T (%v)", test.err, err, err)
+			} else if abort, ok := test.err.(FeatureAbortError); ok && err != abort {
+				t.Errorf("Expected error %v, got %v", test.err, err)
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.want, got, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"context"
+	"time"
+)
+
+// How long to wait for a device to answer a query during a scan. The CEC specification requires
+// a response within one second.
+const scanTimeout = time.Second
+
+// A RemoteDevice describes a device found by Scan. MenuLanguage is never queried and stays empty,
+// LastSeen is the time of the last answer.
+type RemoteDevice struct {
+	DeviceState
+
+	// The opcodes of all queries the device didn't answer. The corresponding fields are left
+	// unknown, see DeviceState.
+	Failed []OpCode
+}
+
+// Scans the bus for other devices and queries their physical address, device type, OSD name,
+// vendor ID, CEC version, and power status.
+//
+// If the device is a Poller, all logical addresses are polled first and only present devices are
+// queried. Otherwise, a device is considered present if it answers GivePhysicalAddress. Like
+// Request, Scan depends on Run to dispatch the responses. If ctx is done before the scan is
+// complete, the devices found so far are returned together with ctx.Err().
+func (x *Cec) Scan(ctx context.Context) ([]RemoteDevice, error) {
+	self := x.dev.GetLogicalAddress()
+	poller, canPoll := x.dev.(Poller)
+
+	var devices []RemoteDevice
+	for a := TV; a < Unregistered; a++ {
+		if err := ctx.Err(); err != nil {
+			return devices, err
+		}
+		if a == self {
+			continue
+		}
+		if canPoll {
+			present, err := poller.PollLogicalAddress(a)
+			if err != nil {
+				return devices, err
+			}
+			if !present {
+				continue
+			}
+		}
+		d, present, err := x.queryDevice(ctx, a, canPoll)
+		if err != nil {
+			return devices, err
+		}
+		if present {
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+// Queries all information about the device at a. If the presence of the device is unknown, the
+// device is considered present if it answers the first query.
+func (x *Cec) queryDevice(ctx context.Context, a LogicalAddr, present bool) (RemoteDevice, bool, error) {
+	d := RemoteDevice{
+		DeviceState: unknownDeviceState(a),
+	}
+	queries := []Command{
+		GivePhysicalAddress{},
+		GiveOSDName{},
+		GiveDeviceVendorID{},
+		GetCECVersion{},
+		GiveDevicePowerStatus{},
+	}
+	for _, q := range queries {
+		resp, err := x.scanRequest(ctx, a, q)
+		if ctx.Err() != nil {
+			return d, false, ctx.Err()
+		}
+		if _, ok := err.(Closed); ok {
+			return d, false, err
+		}
+		if err != nil {
+			if !present {
+				return d, false, nil
+			}
+			d.Failed = append(d.Failed, q.Op())
+			continue
+		}
+		present = true
+		d.LastSeen = time.Now()
+
+		switch r := resp.(type) {
+		case ReportPhysicalAddress:
+			d.PhysicalAddress = r.Addr
+			d.DeviceType = r.Type
+		case SetOSDName:
+			d.OSDName = r.Name
+		case DeviceVendorID:
+			d.VendorID = r.VendorID
+		case CECVersion:
+			d.CECVersion = r.Version
+		case ReportPowerStatus:
+			d.PowerStatus = r.Power
+		}
+	}
+	return d, present, nil
+}
+
+func (x *Cec) scanRequest(ctx context.Context, a LogicalAddr, q Command) (Command, error) {
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+	return x.Request(ctx, a, q)
+}
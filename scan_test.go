@@ -0,0 +1,123 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"znkr.io/cec/device/virtual"
+
+	. "znkr.io/cec"
+)
+
+// Attaches a device to the bus and starts handling messages with the default handler and h.
+func startVirtual(t *testing.T, b *virtual.Bus, c virtual.Config, name string, h ...Handler) *Cec {
+	x, err := New(b.Attach(c), Config{OSDName: name, Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	for _, h := range h {
+		x.AddHandler(h)
+	}
+	x.AddHandler(DefaultHandler{})
+	go x.Run()
+	t.Cleanup(func() { x.Close() })
+	return x
+}
+
+func TestScan(t *testing.T) {
+	b := virtual.NewBus()
+	powerOn := HandlerFunc(func(x *Cec, msg Message) bool {
+		if _, ok := msg.Cmd.(GiveDevicePowerStatus); ok {
+			x.Reply(msg.Initiator, ReportPowerStatus{Power: PowerStatusOn})
+			return true
+		}
+		return false
+	})
+	startVirtual(t, b, virtual.Config{
+		LogicalAddr:     TV,
+		PhysicalAddress: 0x0000,
+		DeviceType:      DeviceTypeTV,
+		VendorID:        0x00903e,
+	}, "tv", powerOn)
+	startVirtual(t, b, virtual.Config{
+		LogicalAddr:     AudioSystem,
+		PhysicalAddress: 0x1000,
+		DeviceType:      DeviceTypeAudio,
+		VendorID:        0x000ce7,
+	}, "soundbar")
+	player := startVirtual(t, b, virtual.Config{
+		LogicalAddr:     Playback1,
+		PhysicalAddress: 0x1100,
+		DeviceType:      DeviceTypePlayback,
+	}, "player")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	devices, err := player.Scan(ctx)
+	if err != nil {
+		t.Fatalf("Scan failed: %s", err)
+	}
+
+	expected := []RemoteDevice{
+		{
+			DeviceState: DeviceState{
+				LogicalAddr:     TV,
+				PhysicalAddress: 0x0000,
+				DeviceType:      DeviceTypeTV,
+				OSDName:         "tv",
+				VendorID:        0x00903e,
+				CECVersion:      0x04,
+				PowerStatus:     PowerStatusOn,
+			},
+		}, {
+			// The soundbar doesn't report its power status.
+			DeviceState: DeviceState{
+				LogicalAddr:     AudioSystem,
+				PhysicalAddress: 0x1000,
+				DeviceType:      DeviceTypeAudio,
+				OSDName:         "soundbar",
+				VendorID:        0x000ce7,
+				CECVersion:      0x04,
+				PowerStatus:     PowerStatusUnknown,
+			},
+			Failed: []OpCode{OpGiveDevicePowerStatus},
+		},
+	}
+	for _, d := range devices {
+		if d.LastSeen.IsZero() {
+			t.Errorf("Expected LastSeen to be set for %s", d.LogicalAddr)
+		}
+	}
+	opts := cmpopts.IgnoreFields(DeviceState{}, "LastSeen")
+	if diff := cmp.Diff(devices, expected, opts); diff != "" {
+		t.Errorf("Unexpected scan result: %s", diff)
+	}
+}
+
+func TestScan_Canceled(t *testing.T) {
+	b := virtual.NewBus()
+	player := startVirtual(t, b, virtual.Config{LogicalAddr: Playback1}, "player")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := player.Scan(ctx); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Keeps the status of a device feature, e.g. the deck status, and reports it to the devices that
+// asked for it with a status request like GiveDeckStatus.
+type statusReporter struct {
+	mtx         sync.Mutex
+	status      Command // The status report, e.g. DeckStatus.
+	subscribers map[LogicalAddr]bool
+}
+
+func newStatusReporter(status Command) statusReporter {
+	return statusReporter{
+		status:      status,
+		subscribers: make(map[LogicalAddr]bool),
+	}
+}
+
+// Returns the current status report.
+func (r *statusReporter) get() Command {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.status
+}
+
+// Sets the status report. If it changed, it's sent to all devices that asked for status reports
+// with StatusRequestOn. Devices that don't acknowledge the report are removed.
+func (r *statusReporter) set(x *Cec, status Command) {
+	r.mtx.Lock()
+	if r.status == status {
+		r.mtx.Unlock()
+		return
+	}
+	r.status = status
+	subscribers := make([]LogicalAddr, 0, len(r.subscribers))
+	for a := range r.subscribers {
+		subscribers = append(subscribers, a)
+	}
+	r.mtx.Unlock()
+
+	// Sending blocks until the transmission is done, the lock isn't held meanwhile.
+	for _, a := range subscribers {
+		if err := x.Send(a, status); err != nil {
+			x.log.Info("Stopped status reports",
+				slog.String("follower", a.String()),
+				slog.String("opcode", status.Op().String()),
+				slog.Any("error", err))
+			r.mtx.Lock()
+			delete(r.subscribers, a)
+			r.mtx.Unlock()
+		}
+	}
+}
+
+// Handles a status request from the initiator of msg. Unsupported requests are aborted.
+func (r *statusReporter) request(x *Cec, msg Message, req StatusRequest) {
+	r.mtx.Lock()
+	switch req {
+	case StatusRequestOn:
+		r.subscribers[msg.Initiator] = true
+	case StatusRequestOff:
+		delete(r.subscribers, msg.Initiator)
+		r.mtx.Unlock()
+		return
+	case StatusRequestOnce:
+	default:
+		r.mtx.Unlock()
+		x.Reply(msg.Initiator, FeatureAbort{Abort: msg.Cmd.Op(), Reason: AbortInvalidOperand})
+		return
+	}
+	status := r.status
+	r.mtx.Unlock()
+	x.Reply(msg.Initiator, status)
+}
+
+// Aborts msg because the feature refused to carry it out.
+func refuse(x *Cec, msg Message, err error) {
+	x.log.Warn("Refused command", append(messageAttrs(msg), slog.Any("error", err))...)
+	x.Reply(msg.Initiator, FeatureAbort{Abort: msg.Cmd.Op(), Reason: AbortRefused})
+}
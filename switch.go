@@ -0,0 +1,120 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// The SwitchHandler implements Routing Control for HDMI switches and repeaters. It follows
+// SetStreamPath, RoutingChange, and RoutingInformation messages for devices connected to the
+// switch and reports the active route with RoutingInformation when the route leads to the switch.
+//
+// Inputs are identified by their port number, starting with 1. The physical address of the device
+// connected to port n is the physical address of the switch with the next digit set to n.
+type SwitchHandler struct {
+	selectInput func(port int) error
+
+	mtx  sync.Mutex
+	port int // The selected input, 0 if unknown.
+}
+
+// Creates a new SwitchHandler. selectInput is called to switch to another input port, port is the
+// currently selected input or 0 if it's unknown.
+func NewSwitchHandler(selectInput func(port int) error, port int) *SwitchHandler {
+	return &SwitchHandler{
+		selectInput: selectInput,
+		port:        port,
+	}
+}
+
+// Returns the selected input port or 0 if it's unknown.
+func (h *SwitchHandler) Input() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.port
+}
+
+// Switches to another input, e.g. because the user pressed a button on the switch, and broadcasts
+// the RoutingChange.
+func (h *SwitchHandler) SelectInput(x *Cec, port int) error {
+	own := x.dev.GetPhysicalAddress()
+	to, ok := own.Child(port)
+	if !ok {
+		return fmt.Errorf("invalid input port %d for switch at %s", port, own)
+	}
+	old, changed, err := h.set(port)
+	if err != nil || !changed {
+		return err
+	}
+	from, ok := own.Child(old)
+	if !ok {
+		from = own
+	}
+	return x.Send(Broadcast, RoutingChange{From: from, To: to})
+}
+
+// Selects the input port and returns the previous port and whether it changed.
+func (h *SwitchHandler) set(port int) (int, bool, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	old := h.port
+	if old == port {
+		return old, false, nil
+	}
+	if err := h.selectInput(port); err != nil {
+		return old, false, err
+	}
+	h.port = port
+	return old, true, nil
+}
+
+// Follows the route to addr, taken from msg. If addr is the switch itself, the active route is
+// reported. If it's connected to the switch, the input leading to it is selected. Returns false if
+// addr is neither.
+func (h *SwitchHandler) route(x *Cec, msg Message, addr PhysicalAddress) bool {
+	own := x.dev.GetPhysicalAddress()
+	switch {
+	case addr == own:
+		if in, ok := own.Child(h.Input()); ok {
+			x.Reply(Broadcast, RoutingInformation{Addr: in})
+		}
+		return true
+
+	case addr.IsDescendant(own):
+		port := addr.digit(own.Depth())
+		if _, _, err := h.set(port); err != nil {
+			x.log.Warn("Failed to select input",
+				append(messageAttrs(msg), slog.Int("port", port), slog.Any("error", err))...)
+		}
+		return true
+	}
+	return false
+}
+
+// SwitchHandler implements Handler.
+func (h *SwitchHandler) HandleMessage(x *Cec, msg Message) bool {
+	switch cmd := msg.Cmd.(type) {
+	case SetStreamPath:
+		return h.route(x, msg, cmd.Addr)
+	case RoutingChange:
+		return h.route(x, msg, cmd.To)
+	case RoutingInformation:
+		return h.route(x, msg, cmd.Addr)
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"znkr.io/cec/device/virtual"
+
+	. "znkr.io/cec"
+)
+
+func TestSwitchHandler(t *testing.T) {
+	broadcast := func(cmd Command) Message {
+		return Message{Initiator: TV, Follower: Broadcast, Cmd: cmd}
+	}
+	tests := []struct {
+		name     string
+		port     int
+		in       []Message
+		handled  []bool
+		selected []int
+		out      []Packet
+	}{
+		{
+			name:     "set_stream_path",
+			port:     1,
+			in:       []Message{broadcast(SetStreamPath{Addr: 0x1200})},
+			handled:  []bool{true},
+			selected: []int{2},
+		}, {
+			name:     "set_stream_path_below_input",
+			port:     1,
+			in:       []Message{broadcast(SetStreamPath{Addr: 0x1310})},
+			handled:  []bool{true},
+			selected: []int{3},
+		}, {
+			name:     "set_stream_path_same_input",
+			port:     2,
+			in:       []Message{broadcast(SetStreamPath{Addr: 0x1200})},
+			handled:  []bool{true},
+			selected: nil,
+		}, {
+			name:    "set_stream_path_self",
+			port:    2,
+			in:      []Message{broadcast(SetStreamPath{Addr: 0x1000})},
+			handled: []bool{true},
+			out: []Packet{
+				{Unregistered, Broadcast, OpRoutingInformation, []byte{0x12, 0x00}},
+			},
+		}, {
+			name:    "set_stream_path_elsewhere",
+			port:    2,
+			in:      []Message{broadcast(SetStreamPath{Addr: 0x2000})},
+			handled: []bool{false},
+		}, {
+			// The route changed to the switch, the switch reports the active route below it.
+			name:    "routing_change_to_self",
+			port:    3,
+			in:      []Message{broadcast(RoutingChange{From: 0x2000, To: 0x1000})},
+			handled: []bool{true},
+			out: []Packet{
+				{Unregistered, Broadcast, OpRoutingInformation, []byte{0x13, 0x00}},
+			},
+		}, {
+			name:     "routing_change_below",
+			port:     3,
+			in:       []Message{broadcast(RoutingChange{From: 0x2000, To: 0x1100})},
+			handled:  []bool{true},
+			selected: []int{1},
+		}, {
+			name:    "routing_information_to_self",
+			port:    1,
+			in:      []Message{broadcast(RoutingInformation{Addr: 0x1000})},
+			handled: []bool{true},
+			out: []Packet{
+				{Unregistered, Broadcast, OpRoutingInformation, []byte{0x11, 0x00}},
+			},
+		}, {
+			name:    "unknown_input",
+			port:    0,
+			in:      []Message{broadcast(RoutingInformation{Addr: 0x1000})},
+			handled: []bool{true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := virtual.NewBus()
+			x, err := New(b.Attach(virtual.Config{
+				LogicalAddr:     Unregistered,
+				PhysicalAddress: 0x1000,
+				DeviceType:      DeviceTypeSwitch,
+			}), Config{OSDName: "switch", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			var selected []int
+			h := NewSwitchHandler(func(port int) error {
+				selected = append(selected, port)
+				return nil
+			}, test.port)
+			for i, msg := range test.in {
+				if handled := h.HandleMessage(x, msg); handled != test.handled[i] {
+					t.Errorf("Expected HandleMessage(%v) = %t, got %t", msg, test.handled[i], handled)
+				}
+			}
+			if diff := cmp.Diff(selected, test.selected); diff != "" {
+				t.Errorf("Expected selected inputs %v, got %v: %s", test.selected, selected, diff)
+			}
+			if diff := cmp.Diff(b.Transmitted(), test.out, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, b.Transmitted(), diff)
+			}
+		})
+	}
+}
+
+func TestSwitchHandler_SelectInput(t *testing.T) {
+	b := virtual.NewBus()
+	x, err := New(b.Attach(virtual.Config{
+		LogicalAddr:     Unregistered,
+		PhysicalAddress: 0x1000,
+		DeviceType:      DeviceTypeSwitch,
+	}), Config{OSDName: "switch", Logger: discard})
+	if err != nil {
+		t.Fatalf("Error setting up %s", err)
+	}
+	h := NewSwitchHandler(func(port int) error { return nil }, 0)
+	for _, port := range []int{1, 1, 2} {
+		if err := h.SelectInput(x, port); err != nil {
+			t.Errorf("SelectInput(%d) failed: %s", port, err)
+		}
+	}
+	if err := h.SelectInput(x, 16); err == nil {
+		t.Errorf("SelectInput(16) succeeded, expected error")
+	}
+	if h.Input() != 2 {
+		t.Errorf("Expected input 2, got %d", h.Input())
+	}
+	expected := []Packet{
+		{Unregistered, Broadcast, OpRoutingChange, []byte{0x10, 0x00, 0x11, 0x00}},
+		{Unregistered, Broadcast, OpRoutingChange, []byte{0x11, 0x00, 0x12, 0x00}},
+	}
+	if diff := cmp.Diff(b.Transmitted(), expected); diff != "" {
+		t.Errorf("Expected %v, got %v: %s", expected, b.Transmitted(), diff)
+	}
+	if addr, ok := x.ActiveSource(); !ok || addr != 0x1200 {
+		t.Errorf("Expected active source 1.2.0.0, got %s (known: %t)", addr, ok)
+	}
+}
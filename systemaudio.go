@@ -0,0 +1,96 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import "context"
+
+// Asks the audio system to turn system audio mode on or off and returns the mode the audio system
+// reports back. When turning system audio mode on, the audio system is asked to play the audio of
+// this device.
+//
+// Like Request, this depends on Run to dispatch the response.
+func (x *Cec) RequestSystemAudioMode(ctx context.Context, on bool) (bool, error) {
+	req := SystemAudioModeRequest{}
+	if on {
+		addr := x.dev.GetPhysicalAddress()
+		req.Addr = &addr
+	}
+	resp, err := x.Request(ctx, AudioSystem, req)
+	if err != nil {
+		return false, err
+	}
+	return resp.(SetSystemAudioMode).On, nil
+}
+
+// Returns true if system audio mode is on. Like Request, this depends on Run to dispatch the
+// response.
+func (x *Cec) GetSystemAudioMode(ctx context.Context) (bool, error) {
+	resp, err := x.Request(ctx, AudioSystem, GiveSystemAudioModeStatus{})
+	if err != nil {
+		return false, err
+	}
+	return resp.(SystemAudioModeStatus).On, nil
+}
+
+// Returns the volume of the audio system between 0 and 100 and whether it's muted. The volume is
+// negative if the audio system doesn't know it. Like Request, this depends on Run to dispatch the
+// response.
+func (x *Cec) GetAudioStatus(ctx context.Context) (volume int, muted bool, err error) {
+	resp, err := x.Request(ctx, AudioSystem, GiveAudioStatus{})
+	if err != nil {
+		return 0, false, err
+	}
+	status := resp.(ReportAudioStatus)
+	return status.Volume, status.Muted, nil
+}
+
+// Turns the volume of the audio system up by one step and returns the new audio status. See
+// GetAudioStatus.
+func (x *Cec) VolumeUp(ctx context.Context) (volume int, muted bool, err error) {
+	return x.audioKey(ctx, UcVolumeUp)
+}
+
+// Turns the volume of the audio system down by one step and returns the new audio status. See
+// GetAudioStatus.
+func (x *Cec) VolumeDown(ctx context.Context) (volume int, muted bool, err error) {
+	return x.audioKey(ctx, UcVolumeDown)
+}
+
+// Toggles mute on the audio system and returns the new audio status. See GetAudioStatus.
+func (x *Cec) Mute(ctx context.Context) (volume int, muted bool, err error) {
+	return x.audioKey(ctx, UcMute)
+}
+
+// Presses c on the audio system and waits for the ReportAudioStatus the audio system sends in
+// response.
+func (x *Cec) audioKey(ctx context.Context, c UserControl) (int, bool, error) {
+	resp, err := x.exchange(ctx, AudioSystem, OpUserControlPressed, OpReportAudioStatus, func() error {
+		return x.PressKey(AudioSystem, c)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	status := resp.(ReportAudioStatus)
+	return status.Volume, status.Muted, nil
+}
+
+// Sends a key press of c to follower, i.e. UserControlPressed followed by UserControlReleased. The
+// release is only sent if the press was acknowledged.
+func (x *Cec) PressKey(follower LogicalAddr, c UserControl) error {
+	if err := x.Send(follower, UserControlPressed{Pressed: c}); err != nil {
+		return err
+	}
+	return x.Send(follower, UserControlReleased{})
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"znkr.io/cec/device/virtual"
+
+	. "znkr.io/cec"
+)
+
+func TestSystemAudioControl(t *testing.T) {
+	b := virtual.NewBus()
+	h := NewAudioSystemHandler(&output{}, 50)
+	startVirtual(t, b, virtual.Config{
+		LogicalAddr:     AudioSystem,
+		PhysicalAddress: 0x1000,
+		DeviceType:      DeviceTypeAudio,
+	}, "soundbar", h)
+	tv := startVirtual(t, b, virtual.Config{
+		LogicalAddr:     TV,
+		PhysicalAddress: 0x0000,
+		DeviceType:      DeviceTypeTV,
+	}, "tv")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	on, err := tv.RequestSystemAudioMode(ctx, true)
+	if err != nil || !on {
+		t.Fatalf("RequestSystemAudioMode(true) = %t, %v, expected true", on, err)
+	}
+	if on, err := tv.GetSystemAudioMode(ctx); err != nil || !on {
+		t.Errorf("GetSystemAudioMode() = %t, %v, expected true", on, err)
+	}
+
+	type status struct {
+		Volume int
+		Muted  bool
+	}
+	steps := []struct {
+		name string
+		key  func(ctx context.Context) (int, bool, error)
+		want status
+	}{
+		{"status", tv.GetAudioStatus, status{50, false}},
+		{"volume_up", tv.VolumeUp, status{51, false}},
+		{"volume_up", tv.VolumeUp, status{52, false}},
+		{"volume_down", tv.VolumeDown, status{51, false}},
+		{"mute", tv.Mute, status{51, true}},
+		{"status", tv.GetAudioStatus, status{51, true}},
+		{"unmute", tv.Mute, status{51, false}},
+	}
+	for _, s := range steps {
+		volume, muted, err := s.key(ctx)
+		if err != nil {
+			t.Fatalf("%s failed: %s", s.name, err)
+		}
+		if diff := cmp.Diff(status{volume, muted}, s.want); diff != "" {
+			t.Errorf("%s: expected %v, got %v: %s", s.name, s.want, status{volume, muted}, diff)
+		}
+	}
+
+	on, err = tv.RequestSystemAudioMode(ctx, false)
+	if err != nil || on {
+		t.Fatalf("RequestSystemAudioMode(false) = %t, %v, expected false", on, err)
+	}
+	if h.SystemAudioMode() {
+		t.Errorf("Expected system audio mode to be off")
+	}
+
+	// The key presses must be released.
+	var presses, releases int
+	for _, p := range b.Transmitted() {
+		switch p.Op {
+		case OpUserControlPressed:
+			presses++
+		case OpUserControlReleased:
+			releases++
+		}
+	}
+	if presses != 5 || releases != 5 {
+		t.Errorf("Expected 5 presses and releases, got %d presses and %d releases", presses, releases)
+	}
+}
+
+func TestSystemAudioControl_NoAudioSystem(t *testing.T) {
+	b := virtual.NewBus()
+	tv := startVirtual(t, b, virtual.Config{
+		LogicalAddr:     TV,
+		PhysicalAddress: 0x0000,
+		DeviceType:      DeviceTypeTV,
+	}, "tv")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, _, err := tv.VolumeUp(ctx); err != (TransmitError{Status: TxNack}) {
+		t.Errorf("Expected %v, got %v", TransmitError{Status: TxNack}, err)
+	}
+	// The release isn't sent if the press wasn't acknowledged.
+	if n := len(b.Transmitted()); n != 1 {
+		t.Errorf("Expected 1 transmitted frame, got %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+import "sort"
+
+// A Topology arranges devices into the HDMI tree implied by their physical addresses. The root
+// of the tree is always RootAddress, even if no device with that address is known.
+//
+// Not every device in an HDMI chain supports CEC, e.g. a passive switch. The topology therefore
+// links each device to its closest known ancestor.
+type Topology struct {
+	devices  map[PhysicalAddress][]RemoteDevice
+	unplaced []RemoteDevice
+}
+
+// Creates a topology from a list of devices, e.g. the result of Scan. Devices with an unknown or
+// malformed physical address can't be placed in the tree, they are available from Unplaced.
+func NewTopology(devices []RemoteDevice) *Topology {
+	t := &Topology{
+		devices: map[PhysicalAddress][]RemoteDevice{
+			RootAddress: nil,
+		},
+	}
+	for _, d := range devices {
+		if !d.PhysicalAddress.Valid() {
+			t.unplaced = append(t.unplaced, d)
+			continue
+		}
+		t.devices[d.PhysicalAddress] = append(t.devices[d.PhysicalAddress], d)
+	}
+	return t
+}
+
+// Returns the devices that aren't part of the tree because their physical address is unknown or
+// malformed, e.g. because they didn't answer GivePhysicalAddress during a Scan.
+func (t *Topology) Unplaced() []RemoteDevice {
+	return t.unplaced
+}
+
+// Returns all physical addresses in the topology in depth-first order.
+func (t *Topology) Addresses() []PhysicalAddress {
+	addrs := make([]PhysicalAddress, 0, len(t.devices))
+	for a := range t.devices {
+		addrs = append(addrs, a)
+	}
+	// Physical addresses sort in depth-first order.
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs
+}
+
+// Returns the devices with the physical address a. A device can have multiple logical addresses,
+// so there can be more than one.
+func (t *Topology) Devices(a PhysicalAddress) []RemoteDevice {
+	return t.devices[a]
+}
+
+// Returns the closest ancestor of a that is part of the topology. The root has no parent.
+func (t *Topology) Parent(a PhysicalAddress) (PhysicalAddress, bool) {
+	for p, ok := a.Parent(); ok; p, ok = p.Parent() {
+		if _, known := t.devices[p]; known {
+			return p, true
+		}
+	}
+	return RootAddress, false
+}
+
+// Returns the addresses whose closest known ancestor is a, in depth-first order.
+func (t *Topology) Children(a PhysicalAddress) []PhysicalAddress {
+	var children []PhysicalAddress
+	for _, c := range t.Addresses() {
+		if p, ok := t.Parent(c); ok && p == a {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// Returns the addresses in the topology on the path from the root to a, including both. Returns
+// nil if a is not part of the topology.
+func (t *Topology) PathTo(a PhysicalAddress) []PhysicalAddress {
+	if _, ok := t.devices[a]; !ok {
+		return nil
+	}
+	var path []PhysicalAddress
+	for _, p := range a.Path() {
+		if _, ok := t.devices[p]; ok {
+			path = append(path, p)
+		}
+	}
+	return path
+}
+
+// Returns true if a is connected to ancestor in the topology, directly or through other devices.
+// Both addresses must be part of the topology.
+func (t *Topology) IsDescendant(a, ancestor PhysicalAddress) bool {
+	path := t.PathTo(a)
+	for i := 0; i < len(path)-1; i++ {
+		if path[i] == ancestor {
+			return true
+		}
+	}
+	return false
+}
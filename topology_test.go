@@ -0,0 +1,99 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "znkr.io/cec"
+)
+
+func remoteDevice(l LogicalAddr, p PhysicalAddress) RemoteDevice {
+	return RemoteDevice{DeviceState: DeviceState{LogicalAddr: l, PhysicalAddress: p}}
+}
+
+func TestTopology(t *testing.T) {
+	// TV with a soundbar on input 1 and a player behind the soundbar. The switch on input 2 doesn't
+	// support CEC, the players behind it are connected to its inputs 1 and 2.
+	topo := NewTopology([]RemoteDevice{
+		remoteDevice(TV, 0x0000),
+		remoteDevice(AudioSystem, 0x1000),
+		remoteDevice(Playback1, 0x1100),
+		remoteDevice(Playback2, 0x2100),
+		remoteDevice(Playback3, 0x2200),
+		remoteDevice(Tuner1, 0x2200),
+	})
+
+	if diff := cmp.Diff(topo.Addresses(), []PhysicalAddress{0x0000, 0x1000, 0x1100, 0x2100, 0x2200}); diff != "" {
+		t.Errorf("Unexpected addresses: %s", diff)
+	}
+	if n := len(topo.Devices(0x2200)); n != 2 {
+		t.Errorf("Expected 2 devices at 2.2.0.0, got %d", n)
+	}
+	if diff := cmp.Diff(topo.Children(0x0000), []PhysicalAddress{0x1000, 0x2100, 0x2200}); diff != "" {
+		t.Errorf("Unexpected children of the root: %s", diff)
+	}
+	if diff := cmp.Diff(topo.Children(0x1000), []PhysicalAddress{0x1100}); diff != "" {
+		t.Errorf("Unexpected children of 1.0.0.0: %s", diff)
+	}
+	if p, ok := topo.Parent(0x2100); !ok || p != 0x0000 {
+		t.Errorf("Expected parent of 2.1.0.0 to be the root, got %s (%t)", p, ok)
+	}
+	if _, ok := topo.Parent(0x0000); ok {
+		t.Errorf("Expected root to have no parent.")
+	}
+	if diff := cmp.Diff(topo.PathTo(0x1100), []PhysicalAddress{0x0000, 0x1000, 0x1100}); diff != "" {
+		t.Errorf("Unexpected path to 1.1.0.0: %s", diff)
+	}
+	if path := topo.PathTo(0x3000); path != nil {
+		t.Errorf("Expected no path to unknown address, got %v", path)
+	}
+	descendants := []struct {
+		a, ancestor PhysicalAddress
+		want        bool
+	}{
+		{0x1100, 0x1000, true},
+		{0x1100, 0x0000, true},
+		{0x2100, 0x1000, false},
+		{0x1000, 0x1000, false},
+		// 2.0.0.0 is the switch without CEC, it's not part of the topology.
+		{0x2100, 0x2000, false},
+		// 1.2.0.0 is below the soundbar, but not part of the topology.
+		{0x1200, 0x1000, false},
+	}
+	for _, d := range descendants {
+		if got := topo.IsDescendant(d.a, d.ancestor); got != d.want {
+			t.Errorf("IsDescendant(%s, %s) = %t, want %t", d.a, d.ancestor, got, d.want)
+		}
+	}
+}
+
+func TestTopology_Unplaced(t *testing.T) {
+	// Devices with a malformed or unknown physical address, e.g. because they didn't answer during
+	// a scan, are kept out of the tree.
+	unplaced := []RemoteDevice{
+		remoteDevice(Playback1, 0x1020),
+		remoteDevice(Playback2, InvalidAddress),
+	}
+	topo := NewTopology(append([]RemoteDevice{remoteDevice(TV, 0x0000)}, unplaced...))
+	if diff := cmp.Diff(topo.Addresses(), []PhysicalAddress{0x0000}); diff != "" {
+		t.Errorf("Unexpected addresses: %s", diff)
+	}
+	if diff := cmp.Diff(topo.Unplaced(), unplaced); diff != "" {
+		t.Errorf("Unexpected unplaced devices: %s", diff)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec
+
+// A Tuner receives TV services, e.g. a DVB receiver. The methods return the tuner status after the
+// change. If a method returns an error, the command is refused.
+type Tuner interface {
+	SelectAnalogService(s AnalogService) (TunerStatus, error)
+	SelectDigitalService(s DigitalService) (TunerStatus, error)
+
+	// Tunes to the next or previous service.
+	Step(up bool) (TunerStatus, error)
+}
+
+// The TunerHandler implements Tuner Control. It passes SelectAnalogService, SelectDigitalService,
+// TunerStepIncrement, and TunerStepDecrement commands on to a Tuner and answers
+// GiveTunerDeviceStatus. Devices that asked for status reports with StatusRequestOn receive a
+// TunerDeviceStatus whenever the tuner status changes.
+type TunerHandler struct {
+	t      Tuner
+	status statusReporter
+}
+
+// Creates a new TunerHandler with the initial tuner status.
+func NewTunerHandler(t Tuner, status TunerStatus) *TunerHandler {
+	return &TunerHandler{
+		t:      t,
+		status: newStatusReporter(TunerDeviceStatus{Status: status}),
+	}
+}
+
+// Returns the current tuner status.
+func (h *TunerHandler) TunerStatus() TunerStatus {
+	return h.status.get().(TunerDeviceStatus).Status
+}
+
+// Sets the tuner status, e.g. because the tuner was changed by other means than CEC. If the status
+// changed, it's reported to all devices that asked for status reports. Devices that don't
+// acknowledge the report are removed.
+func (h *TunerHandler) SetTunerStatus(x *Cec, status TunerStatus) {
+	h.status.set(x, TunerDeviceStatus{Status: status})
+}
+
+// Runs a tuner command and updates the tuner status if it succeeds. Aborts the message if the
+// command fails.
+func (h *TunerHandler) run(x *Cec, msg Message, f func() (TunerStatus, error)) {
+	status, err := f()
+	if err != nil {
+		refuse(x, msg, err)
+		return
+	}
+	h.SetTunerStatus(x, status)
+}
+
+// TunerHandler implements Handler.
+func (h *TunerHandler) HandleMessage(x *Cec, msg Message) bool {
+	if msg.Follower == Broadcast {
+		return false
+	}
+
+	switch cmd := msg.Cmd.(type) {
+	case GiveTunerDeviceStatus:
+		h.status.request(x, msg, cmd.Request)
+		return true
+
+	case SelectAnalogService:
+		h.run(x, msg, func() (TunerStatus, error) { return h.t.SelectAnalogService(cmd.Service) })
+		return true
+
+	case SelectDigitalService:
+		h.run(x, msg, func() (TunerStatus, error) { return h.t.SelectDigitalService(cmd.Service) })
+		return true
+
+	case TunerStepIncrement:
+		h.run(x, msg, func() (TunerStatus, error) { return h.t.Step(true) })
+		return true
+
+	case TunerStepDecrement:
+		h.run(x, msg, func() (TunerStatus, error) { return h.t.Step(false) })
+		return true
+	}
+	return false
+}
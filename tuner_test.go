@@ -0,0 +1,170 @@
+// Copyright 2017 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cec_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"znkr.io/cec/device/fake"
+
+	. "znkr.io/cec"
+)
+
+// A Tuner that records all calls and tunes to the selected services. Stepping changes the digital
+// service ID.
+type tuner struct {
+	calls  []string
+	status TunerStatus
+	err    error // Returned by all methods if set.
+}
+
+func (t *tuner) tune(name string, status TunerStatus) (TunerStatus, error) {
+	t.calls = append(t.calls, name)
+	if t.err != nil {
+		return TunerStatus{}, t.err
+	}
+	t.status = status
+	return status, nil
+}
+
+func (t *tuner) SelectAnalogService(s AnalogService) (TunerStatus, error) {
+	return t.tune(fmt.Sprintf("analog(%d)", s.Frequency), TunerStatus{Display: TunerDisplayAnalog, Analog: s})
+}
+
+func (t *tuner) SelectDigitalService(s DigitalService) (TunerStatus, error) {
+	return t.tune(fmt.Sprintf("digital(%d)", s.ServiceID), TunerStatus{Display: TunerDisplayDigital, IsDigital: true, Digital: s})
+}
+
+func (t *tuner) Step(up bool) (TunerStatus, error) {
+	status := t.status
+	if up {
+		status.Digital.ServiceID++
+	} else {
+		status.Digital.ServiceID--
+	}
+	return t.tune(fmt.Sprintf("step(%t)", up), status)
+}
+
+// The details of status requests are shared with the DeckHandler and tested there.
+func TestTunerHandler(t *testing.T) {
+	digital := func(id uint16) TunerStatus {
+		return TunerStatus{
+			Display:   TunerDisplayDigital,
+			IsDigital: true,
+			Digital:   DigitalService{System: DigitalDVBT, ServiceID: id},
+		}
+	}
+
+	dvb := func(id byte) []byte {
+		return []byte{byte(TunerDisplayDigital), byte(DigitalDVBT), 0x00, 0x00, 0x00, id, 0x00, 0x00}
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		in     []Packet
+		out    []Packet
+		calls  []string
+		status TunerStatus
+	}{
+		{
+			name: "select_analog_service",
+			in: []Packet{
+				{TV, Tuner1, OpSelectAnalogService, []byte{byte(AnalogTerrestrial), 0x12, 0x34, byte(BroadcastPALI)}},
+			},
+			calls: []string{"analog(4660)"},
+			status: TunerStatus{
+				Display: TunerDisplayAnalog,
+				Analog:  AnalogService{AnalogTerrestrial, 0x1234, BroadcastPALI},
+			},
+		}, {
+			name: "select_digital_service",
+			in: []Packet{
+				{TV, Tuner1, OpSelectDigitalService, []byte{byte(DigitalDVBT), 0x00, 0x00, 0x00, 0x05, 0x00, 0x00}},
+			},
+			calls:  []string{"digital(5)"},
+			status: digital(5),
+		}, {
+			name: "step",
+			in: []Packet{
+				{TV, Tuner1, OpTunerStepIncrement, nil},
+				{TV, Tuner1, OpTunerStepIncrement, nil},
+				{TV, Tuner1, OpTunerStepDecrement, nil},
+			},
+			calls:  []string{"step(true)", "step(true)", "step(false)"},
+			status: digital(2),
+		}, {
+			// Subscribers get a report after every change.
+			name: "status_on",
+			in: []Packet{
+				{TV, Tuner1, OpGiveTunerDeviceStatus, []byte{byte(StatusRequestOn)}},
+				{TV, Tuner1, OpTunerStepIncrement, nil},
+				{TV, Tuner1, OpSelectDigitalService, dvb(5)[1:]},
+				{TV, Tuner1, OpSelectAnalogService, []byte{byte(AnalogTerrestrial), 0x12, 0x34, byte(BroadcastPALI)}},
+			},
+			out: []Packet{
+				{Tuner1, TV, OpTunerDeviceStatus, dvb(1)},
+				{Tuner1, TV, OpTunerDeviceStatus, dvb(2)},
+				{Tuner1, TV, OpTunerDeviceStatus, dvb(5)},
+				{Tuner1, TV, OpTunerDeviceStatus, []byte{byte(TunerDisplayAnalog), byte(AnalogTerrestrial), 0x12, 0x34, byte(BroadcastPALI)}},
+			},
+			calls: []string{"step(true)", "digital(5)", "analog(4660)"},
+			status: TunerStatus{
+				Display: TunerDisplayAnalog,
+				Analog:  AnalogService{AnalogTerrestrial, 0x1234, BroadcastPALI},
+			},
+		}, {
+			name: "refused",
+			err:  errors.New("no signal"),
+			in: []Packet{
+				{TV, Tuner1, OpGiveTunerDeviceStatus, []byte{byte(StatusRequestOn)}},
+				{TV, Tuner1, OpSelectDigitalService, dvb(5)[1:]},
+			},
+			out: []Packet{
+				{Tuner1, TV, OpTunerDeviceStatus, dvb(1)},
+				{Tuner1, TV, OpFeatureAbort, []byte{byte(OpSelectDigitalService), byte(AbortRefused)}},
+			},
+			calls:  []string{"digital(5)"},
+			status: digital(1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tu := &tuner{status: digital(1), err: test.err}
+			h := NewTunerHandler(tu, digital(1))
+			d := fake.New(Tuner1, DeviceTypeTuner)
+			c, err := New(d, Config{OSDName: "test", Logger: discard})
+			if err != nil {
+				t.Fatalf("Error setting up %s", err)
+			}
+			c.AddHandler(h)
+			actual := d.Run(test.in, func() { c.Run() })
+			if diff := cmp.Diff(actual, test.out, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Expected %v, got %v: %s", test.out, actual, diff)
+			}
+			if diff := cmp.Diff(tu.calls, test.calls); diff != "" {
+				t.Errorf("Expected calls %v, got %v: %s", test.calls, tu.calls, diff)
+			}
+			if diff := cmp.Diff(h.TunerStatus(), test.status); diff != "" {
+				t.Errorf("Unexpected tuner status: %s", diff)
+			}
+		})
+	}
+}
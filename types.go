@@ -20,6 +20,19 @@ package cec
 //go:generate stringer -type=LogicalAddr
 //go:generate stringer -type=DeviceType
 //go:generate stringer -type=AbortReason
+//go:generate stringer -type=TxStatus
+//go:generate stringer -type=KeyEventType
+//go:generate stringer -type=StatusRequest
+//go:generate stringer -type=DeckInfo
+//go:generate stringer -type=DeckControlMode
+//go:generate stringer -type=PlayMode
+//go:generate stringer -type=MenuRequestType
+//go:generate stringer -type=MenuState
+//go:generate stringer -type=DisplayControl
+//go:generate stringer -type=AnalogBroadcastType
+//go:generate stringer -type=BroadcastSystem
+//go:generate stringer -type=DigitalBroadcastSystem
+//go:generate stringer -type=TunerDisplayInfo
 
 import "fmt"
 
@@ -41,6 +54,83 @@ func (a PhysicalAddress) Bytes() []byte {
 	}
 }
 
+// The physical address of the root of the HDMI tree, i.e. the TV.
+const RootAddress PhysicalAddress = 0x0000
+
+// F.F.F.F is used by the specification to denote an invalid or unknown physical address.
+const InvalidAddress PhysicalAddress = 0xffff
+
+// Returns the digit at position i, with 0 being the most significant digit.
+func (a PhysicalAddress) digit(i int) int {
+	return int(a>>(12-4*uint(i))) & 0x0f
+}
+
+// Returns true if a is a well-formed physical address, i.e. if there is no non-zero digit after a
+// zero digit. InvalidAddress isn't valid.
+func (a PhysicalAddress) Valid() bool {
+	if a == InvalidAddress {
+		return false
+	}
+	zero := false
+	for i := 0; i < 4; i++ {
+		if a.digit(i) == 0 {
+			zero = true
+		} else if zero {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the depth of a in the HDMI tree, i.e. the number of leading non-zero digits. The root
+// has depth 0.
+func (a PhysicalAddress) Depth() int {
+	for i := 0; i < 4; i++ {
+		if a.digit(i) == 0 {
+			return i
+		}
+	}
+	return 4
+}
+
+// Returns the physical address of the device a is connected to. The root has no parent.
+func (a PhysicalAddress) Parent() (PhysicalAddress, bool) {
+	d := a.Depth()
+	if d == 0 {
+		return RootAddress, false
+	}
+	return a &^ (0xf << (16 - 4*uint(d))), true
+}
+
+// Returns the physical address of the device connected to input port of a. Ports are numbered
+// from 1 to 15. Returns false if the port is out of range or if a is a leaf of the tree.
+func (a PhysicalAddress) Child(port int) (PhysicalAddress, bool) {
+	d := a.Depth()
+	if d == 4 || port < 1 || port > 15 {
+		return InvalidAddress, false
+	}
+	return a | PhysicalAddress(port)<<(12-4*uint(d)), true
+}
+
+// Returns true if a is connected to ancestor, directly or through other devices.
+func (a PhysicalAddress) IsDescendant(ancestor PhysicalAddress) bool {
+	d := ancestor.Depth()
+	if a.Depth() <= d {
+		return false
+	}
+	mask := PhysicalAddress(0xffff) << (16 - 4*uint(d))
+	return a&mask == ancestor&mask
+}
+
+// Returns the physical addresses from the root to a, including both.
+func (a PhysicalAddress) Path() []PhysicalAddress {
+	path := []PhysicalAddress{a}
+	for p, ok := a.Parent(); ok; p, ok = p.Parent() {
+		path = append([]PhysicalAddress{p}, path...)
+	}
+	return path
+}
+
 // A logical HDMI CEC address.
 type LogicalAddr byte
 
@@ -87,6 +177,7 @@ const (
 	PowerStatusStandby           PowerStatus = 0x01
 	PowerStatusOnTransition      PowerStatus = 0x02
 	PowerStatusStandbyTransition PowerStatus = 0x03
+	PowerStatusUnknown           PowerStatus = 0xff // Not a valid operand, used for unknown states.
 )
 
 // Representation of an HDMI CEC opcode.
@@ -245,6 +336,198 @@ const (
 	AbortRefused             AbortReason = 0x04
 )
 
+// Requests a single status report or enables or disables status reports on every change.
+type StatusRequest byte
+
+const (
+	StatusRequestOn   StatusRequest = 0x01 // Report now and on every change.
+	StatusRequestOff  StatusRequest = 0x02 // Stop reporting changes.
+	StatusRequestOnce StatusRequest = 0x03 // Report once.
+)
+
+// The state of a deck.
+type DeckInfo byte
+
+const (
+	DeckInfoPlay               DeckInfo = 0x11
+	DeckInfoRecord             DeckInfo = 0x12
+	DeckInfoPlayReverse        DeckInfo = 0x13
+	DeckInfoStill              DeckInfo = 0x14
+	DeckInfoSlow               DeckInfo = 0x15
+	DeckInfoSlowReverse        DeckInfo = 0x16
+	DeckInfoFastForward        DeckInfo = 0x17
+	DeckInfoFastReverse        DeckInfo = 0x18
+	DeckInfoNoMedia            DeckInfo = 0x19
+	DeckInfoStop               DeckInfo = 0x1A
+	DeckInfoSkipForward        DeckInfo = 0x1B
+	DeckInfoSkipReverse        DeckInfo = 0x1C
+	DeckInfoIndexSearchForward DeckInfo = 0x1D
+	DeckInfoIndexSearchReverse DeckInfo = 0x1E
+	DeckInfoOther              DeckInfo = 0x1F
+)
+
+// Deck control operations.
+type DeckControlMode byte
+
+const (
+	DeckControlSkipForward DeckControlMode = 0x01 // Skip forward or wind.
+	DeckControlSkipReverse DeckControlMode = 0x02 // Skip reverse or rewind.
+	DeckControlStop        DeckControlMode = 0x03
+	DeckControlEject       DeckControlMode = 0x04
+)
+
+// Playback modes.
+type PlayMode byte
+
+const (
+	PlayFastForwardMin PlayMode = 0x05
+	PlayFastForwardMed PlayMode = 0x06
+	PlayFastForwardMax PlayMode = 0x07
+	PlayFastReverseMin PlayMode = 0x09
+	PlayFastReverseMed PlayMode = 0x0A
+	PlayFastReverseMax PlayMode = 0x0B
+	PlaySlowForwardMin PlayMode = 0x15
+	PlaySlowForwardMed PlayMode = 0x16
+	PlaySlowForwardMax PlayMode = 0x17
+	PlaySlowReverseMin PlayMode = 0x19
+	PlaySlowReverseMed PlayMode = 0x1A
+	PlaySlowReverseMax PlayMode = 0x1B
+	PlayReverse        PlayMode = 0x20
+	PlayForward        PlayMode = 0x24
+	PlayStill          PlayMode = 0x25 // Pause.
+)
+
+// The type of a MenuRequest.
+type MenuRequestType byte
+
+const (
+	MenuRequestActivate   MenuRequestType = 0x00
+	MenuRequestDeactivate MenuRequestType = 0x01
+	MenuRequestQuery      MenuRequestType = 0x02
+)
+
+// Whether the menu of a device is active.
+type MenuState byte
+
+const (
+	MenuActivated   MenuState = 0x00
+	MenuDeactivated MenuState = 0x01
+)
+
+// How long a SetOSDString is displayed.
+type DisplayControl byte
+
+const (
+	DisplayDefaultTime   DisplayControl = 0x00
+	DisplayUntilCleared  DisplayControl = 0x40
+	DisplayClearPrevious DisplayControl = 0x80 // Clears a message displayed until cleared.
+)
+
+// The broadcast type of an analog service.
+type AnalogBroadcastType byte
+
+const (
+	AnalogCable       AnalogBroadcastType = 0x00
+	AnalogSatellite   AnalogBroadcastType = 0x01
+	AnalogTerrestrial AnalogBroadcastType = 0x02
+)
+
+// The broadcast system of an analog service.
+type BroadcastSystem byte
+
+const (
+	BroadcastPALBG       BroadcastSystem = 0x00
+	BroadcastSECAMLPrime BroadcastSystem = 0x01 // SECAM L'
+	BroadcastPALM        BroadcastSystem = 0x02
+	BroadcastNTSCM       BroadcastSystem = 0x03
+	BroadcastPALI        BroadcastSystem = 0x04
+	BroadcastSECAMDK     BroadcastSystem = 0x05
+	BroadcastSECAMBG     BroadcastSystem = 0x06
+	BroadcastSECAML      BroadcastSystem = 0x07
+	BroadcastPALDK       BroadcastSystem = 0x08
+	BroadcastOther       BroadcastSystem = 0x1F
+)
+
+// The broadcast system of a digital service.
+type DigitalBroadcastSystem byte
+
+const (
+	DigitalARIB            DigitalBroadcastSystem = 0x00
+	DigitalATSC            DigitalBroadcastSystem = 0x01
+	DigitalDVB             DigitalBroadcastSystem = 0x02
+	DigitalARIBBS          DigitalBroadcastSystem = 0x08
+	DigitalARIBCS          DigitalBroadcastSystem = 0x09
+	DigitalARIBT           DigitalBroadcastSystem = 0x0A
+	DigitalATSCCable       DigitalBroadcastSystem = 0x10
+	DigitalATSCSatellite   DigitalBroadcastSystem = 0x11
+	DigitalATSCTerrestrial DigitalBroadcastSystem = 0x12
+	DigitalDVBC            DigitalBroadcastSystem = 0x18
+	DigitalDVBS            DigitalBroadcastSystem = 0x19
+	DigitalDVBS2           DigitalBroadcastSystem = 0x1A
+	DigitalDVBT            DigitalBroadcastSystem = 0x1B
+)
+
+// What a tuner is displaying.
+type TunerDisplayInfo byte
+
+const (
+	TunerDisplayDigital TunerDisplayInfo = 0x00 // Displaying the digital tuner.
+	TunerDisplayNone    TunerDisplayInfo = 0x01 // Not displaying the tuner.
+	TunerDisplayAnalog  TunerDisplayInfo = 0x02 // Displaying the analog tuner.
+)
+
+// An analog TV service.
+type AnalogService struct {
+	BroadcastType AnalogBroadcastType
+	Frequency     uint16 // In multiples of 62.5 kHz.
+	System        BroadcastSystem
+}
+
+// A digital TV service. It's identified by its digital IDs or, if ByChannel is set, by its
+// channel number.
+//
+// The digital IDs of ARIB and DVB services are the transport stream ID, the service ID, and the
+// original network ID. ATSC services are identified by the transport stream ID and the program
+// number, which is stored in ServiceID. OriginalNetworkID is reserved for ATSC and must be 0.
+type DigitalService struct {
+	System    DigitalBroadcastSystem
+	ByChannel bool
+
+	TransportStreamID uint16
+	ServiceID         uint16
+	OriginalNetworkID uint16
+
+	Channel Channel
+}
+
+// A channel number, e.g. 7 or 7.1.
+type Channel struct {
+	TwoPart bool   // If false, the channel number is Minor and Major is unused.
+	Major   uint16 // Must be at most 999.
+	Minor   uint16
+}
+
+// The status of a tuner, see TunerDeviceStatus.
+type TunerStatus struct {
+	Recording bool // Whether the tuner is recording.
+	Display   TunerDisplayInfo
+	IsDigital bool           // Whether the current service is Digital or Analog.
+	Analog    AnalogService  // The current service if IsDigital is false.
+	Digital   DigitalService // The current service if IsDigital is true.
+}
+
+// Result of transmitting a packet on the CEC bus.
+type TxStatus byte
+
+const (
+	TxAck             TxStatus = 0x00 // The packet was acknowledged.
+	TxNack            TxStatus = 0x01 // The packet was not acknowledged.
+	TxArbitrationLost TxStatus = 0x02 // Another device won the bus arbitration.
+	TxLowDrive        TxStatus = 0x03 // Another device pulled the line low during the transmission.
+	TxTimeout         TxStatus = 0x04 // The transmission didn't finish in time.
+	TxError           TxStatus = 0x05 // The transmission failed for another reason.
+)
+
 type opCodeFlags int
 
 const (
@@ -323,3 +606,35 @@ func getOpCodeFlags(op OpCode) (flags opCodeFlags, ok bool) {
 	flags, ok = opCodeMeta[op]
 	return
 }
+
+// Maps requests to the opcode of the expected response.
+var opCodeResponse = map[OpCode]OpCode{
+	OpGiveTunerDeviceStatus:     OpTunerDeviceStatus,
+	OpGiveDeckStatus:            OpDeckStatus,
+	OpGiveOSDName:               OpSetOSDName,
+	OpGiveAudioStatus:           OpReportAudioStatus,
+	OpGiveSystemAudioModeStatus: OpSystemAudioModeStatus,
+	OpSystemAudioModeRequest:    OpSetSystemAudioMode,
+	OpGivePhysicalAddress:       OpReportPhysicalAddress,
+	OpRequestActiveSource:       OpActiveSource,
+	OpGiveDeviceVendorID:        OpDeviceVendorID,
+	OpMenuRequest:               OpMenuStatus,
+	OpGiveDevicePowerStatus:     OpReportPowerStatus,
+	OpGetMenuLanguage:           OpSetMenuLanguage,
+	OpGetCECVersion:             OpCECVersion,
+}
+
+func getResponseOpCode(op OpCode) (resp OpCode, ok bool) {
+	resp, ok = opCodeResponse[op]
+	return
+}
+
+// Logical addresses a device of a given type may claim, in the order they are tried. Device types
+// that aren't listed always use Unregistered.
+var logicalAddrCandidates = map[DeviceType][]LogicalAddr{
+	DeviceTypeTV:       {TV, FreeUse},
+	DeviceTypeRec:      {Rec1, Rec2, Rec3},
+	DeviceTypeTuner:    {Tuner1, Tuner2, Tuner3, Tuner4},
+	DeviceTypePlayback: {Playback1, Playback2, Playback3},
+	DeviceTypeAudio:    {AudioSystem},
+}
@@ -16,6 +16,7 @@ package cec
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 )
 
@@ -36,3 +37,87 @@ func TestPhysicalAddress_String(t *testing.T) {
 		t.Errorf("Not true that %q == %q.", s, expected)
 	}
 }
+
+func TestPhysicalAddress_Valid(t *testing.T) {
+	tests := []struct {
+		addr  PhysicalAddress
+		valid bool
+	}{
+		{0x0000, true},
+		{0x1000, true},
+		{0x1200, true},
+		{0x1230, true},
+		{0x1234, true},
+		{0x0100, false},
+		{0x1020, false},
+		{0x1203, false},
+		{0xffff, false},
+	}
+	for _, test := range tests {
+		if valid := test.addr.Valid(); valid != test.valid {
+			t.Errorf("Expected %s.Valid() to be %t, got %t", test.addr, test.valid, valid)
+		}
+	}
+}
+
+func TestPhysicalAddress_Tree(t *testing.T) {
+	tests := []struct {
+		addr   PhysicalAddress
+		depth  int
+		parent PhysicalAddress
+		root   bool
+		path   []PhysicalAddress
+	}{
+		{0x0000, 0, 0x0000, true, []PhysicalAddress{0x0000}},
+		{0x1000, 1, 0x0000, false, []PhysicalAddress{0x0000, 0x1000}},
+		{0x1200, 2, 0x1000, false, []PhysicalAddress{0x0000, 0x1000, 0x1200}},
+		{0x1234, 4, 0x1230, false, []PhysicalAddress{0x0000, 0x1000, 0x1200, 0x1230, 0x1234}},
+	}
+	for _, test := range tests {
+		if depth := test.addr.Depth(); depth != test.depth {
+			t.Errorf("Expected depth of %s to be %d, got %d", test.addr, test.depth, depth)
+		}
+		parent, ok := test.addr.Parent()
+		if ok == test.root || (ok && parent != test.parent) {
+			t.Errorf("Unexpected parent of %s: %s (%t)", test.addr, parent, ok)
+		}
+		if path := test.addr.Path(); !reflect.DeepEqual(path, test.path) {
+			t.Errorf("Expected path to %s to be %v, got %v", test.addr, test.path, path)
+		}
+	}
+}
+
+func TestPhysicalAddress_Child(t *testing.T) {
+	if c, ok := PhysicalAddress(0x1200).Child(3); !ok || c != 0x1230 {
+		t.Errorf("Expected child 1.2.3.0, got %s (%t)", c, ok)
+	}
+	if c, ok := PhysicalAddress(0x0000).Child(15); !ok || c != 0xf000 {
+		t.Errorf("Expected child f.0.0.0, got %s (%t)", c, ok)
+	}
+	if _, ok := PhysicalAddress(0x1234).Child(1); ok {
+		t.Errorf("Expected leaf to have no children.")
+	}
+	if _, ok := PhysicalAddress(0x1000).Child(0); ok {
+		t.Errorf("Expected port 0 to be invalid.")
+	}
+}
+
+func TestPhysicalAddress_IsDescendant(t *testing.T) {
+	tests := []struct {
+		addr, ancestor PhysicalAddress
+		descendant     bool
+	}{
+		{0x1000, 0x0000, true},
+		{0x1230, 0x1000, true},
+		{0x1230, 0x1200, true},
+		{0x1000, 0x1000, false},
+		{0x0000, 0x1000, false},
+		{0x2100, 0x1000, false},
+		{0x1200, 0x1230, false},
+	}
+	for _, test := range tests {
+		if d := test.addr.IsDescendant(test.ancestor); d != test.descendant {
+			t.Errorf("Expected %s.IsDescendant(%s) to be %t, got %t", test.addr, test.ancestor, test.descendant, d)
+		}
+	}
+}
@@ -16,7 +16,16 @@ package cec
 
 func isValidOsdName(s string) bool {
 	// Must be between 1 and 14 bytes long
-	if len(s) < 1 || len(s) > 14 {
+	return isValidOsdText(s, 14)
+}
+
+func isValidOsdString(s string) bool {
+	// Must be between 1 and 13 bytes long
+	return isValidOsdText(s, 13)
+}
+
+func isValidOsdText(s string, max int) bool {
+	if len(s) < 1 || len(s) > max {
 		return false
 	}
 	// Each byte must be in [0x20, 0x7e] (ASCII).
@@ -28,6 +37,19 @@ func isValidOsdName(s string) bool {
 	return true
 }
 
+func isValidLanguage(s string) bool {
+	// ISO 639-2 codes are three lower case ASCII letters.
+	if len(s) != 3 {
+		return false
+	}
+	for _, b := range s {
+		if b < 'a' || b > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
 func isValidVendorId(id uint32) bool {
 	return id <= 0xffffff
 }